@@ -0,0 +1,124 @@
+package autocpp
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// IsArchive checks if the given filename has the extension of an archive that can be read by OpenArchive
+func IsArchive(filename string) bool {
+	return archiveKind(filename) != ""
+}
+
+func archiveKind(filename string) string {
+	lowerFilename := strings.ToLower(filename)
+	for _, suffix := range []string{".tar.gz", ".tgz", ".tar.xz", ".txz", ".tar.bz2", ".tbz2", ".tar", ".zip"} {
+		if strings.HasSuffix(lowerFilename, suffix) {
+			return suffix
+		}
+	}
+	return ""
+}
+
+// isArchivedSourceFile checks if a file in an archive has the extension of a C or C++ file or header,
+// so that only those are kept in memory
+func isArchivedSourceFile(filename string) bool {
+	return kindOf(filename) != otherFile || isHeaderFile(filename)
+}
+
+// OpenArchive reads the C and C++ files and headers of a .tar.gz, .tar.xz, .tar.bz2, .tar or .zip archive
+// into memory and returns them as a read-only file system, without extracting anything to disk.
+// Decompressing .tar.xz archives requires the xz utility to be installed.
+func OpenArchive(filename string) (fs.FS, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	switch archiveKind(filename) {
+	case ".zip":
+		return readZip(data)
+	case ".tar":
+		return readTar(bytes.NewReader(data))
+	case ".tar.gz", ".tgz":
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		defer r.Close()
+		return readTar(r)
+	case ".tar.bz2", ".tbz2":
+		return readTar(bzip2.NewReader(bytes.NewReader(data)))
+	case ".tar.xz", ".txz":
+		// There is no xz decompressor in the standard library
+		if _, err := exec.LookPath("xz"); err != nil {
+			return nil, fmt.Errorf("the xz utility must be installed for reading %s: %w", filename, err)
+		}
+		cmd := exec.Command("xz", "--decompress", "--stdout")
+		cmd.Stdin = bytes.NewReader(data)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		decompressed, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("could not decompress %s with xz: %w: %s", filename, err, strings.TrimSpace(stderr.String()))
+		}
+		return readTar(bytes.NewReader(decompressed))
+	}
+	return nil, fmt.Errorf("%s is not a supported archive", filename)
+}
+
+func readTar(r io.Reader) (fs.FS, error) {
+	mfs := newMemFS()
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !header.FileInfo().Mode().IsRegular() || !isArchivedSourceFile(header.Name) {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		mfs.add(header.Name, data, header.ModTime)
+	}
+	return mfs, nil
+}
+
+func readZip(data []byte) (fs.FS, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	mfs := newMemFS()
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() || !isArchivedSourceFile(f.Name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		contents, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		mfs.add(f.Name, contents, f.Modified)
+	}
+	return mfs, nil
+}
@@ -0,0 +1,157 @@
+package autocpp
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var archiveTestFiles = map[string]string{
+	"libfoo-1.0/include/foo/foo.h": "#pragma once\n#include <stddef.h>\n",
+	"libfoo-1.0/src/foo.c":         "#include <stdio.h>\n#include \"foo/foo.h\"\n",
+	"libfoo-1.0/README":            "libfoo\n",
+}
+
+// sortedKeys returns the keys of archiveTestFiles in a deterministic order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func tarData(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range sortedKeys(archiveTestFiles) {
+		contents := archiveTestFiles[name]
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(contents)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func writeTarGz(t *testing.T, filename string) {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(tarData(t)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeZip(t *testing.T, filename string) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range sortedKeys(archiveTestFiles) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(archiveTestFiles[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkArchiveSources(t *testing.T, archiveFilename string) {
+	t.Helper()
+	src, err := NewSourcesWithOptions(archiveFilename, SourcesOptions{LocalIncludeDirectories: []string{"libfoo-1.0/include"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shortIncludes := src.ShortIncludes(); !reflect.DeepEqual(shortIncludes, []string{"foo/foo.h", "stddef.h", "stdio.h"}) {
+		t.Errorf("unexpected includes: %v", shortIncludes)
+	}
+	if !hasS(src.AllFilenames(), filepath.Join(archiveFilename, "libfoo-1.0", "src", "foo.c")) {
+		t.Errorf("unexpected filenames: %v", src.AllFilenames())
+	}
+	src.FindIncludePaths(newTestSystem(t))
+	if src.foundMap["foo/foo.h"] != filepath.Join(archiveFilename, "libfoo-1.0", "include", "foo", "foo.h") {
+		t.Errorf("unexpected path for foo/foo.h: %q", src.foundMap["foo/foo.h"])
+	}
+}
+
+func TestSourcesFromTarGz(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "libfoo-1.0.tar.gz")
+	writeTarGz(t, filename)
+	checkArchiveSources(t, filename)
+}
+
+func TestSourcesFromZip(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "libfoo-1.0.zip")
+	writeZip(t, filename)
+	checkArchiveSources(t, filename)
+}
+
+func TestSourcesFromTarXz(t *testing.T) {
+	if _, err := exec.LookPath("xz"); err != nil {
+		t.Skip("xz is not installed")
+	}
+	dir := t.TempDir()
+	tarFilename := filepath.Join(dir, "libfoo-1.0.tar")
+	if err := os.WriteFile(tarFilename, tarData(t), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.Command("xz", tarFilename).Run(); err != nil {
+		t.Fatal(err)
+	}
+	checkArchiveSources(t, tarFilename+".xz")
+}
+
+func TestArchiveFS(t *testing.T) {
+	fsys, err := readTar(bytes.NewReader(tarData(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(fsys, "libfoo-1.0/include/foo/foo.h", "libfoo-1.0/src/foo.c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat(fsys, "libfoo-1.0/README"); err == nil {
+		t.Errorf("expected only the C and C++ files to be read from the archive")
+	}
+}
+
+func TestSourcesFromTarXzWithoutXz(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	filename := filepath.Join(t.TempDir(), "libfoo-1.0.tar.xz")
+	if err := os.WriteFile(filename, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSources(filename, false); err == nil || !strings.Contains(err.Error(), "xz utility must be installed") {
+		t.Errorf("expected an error about xz not being installed, got %v", err)
+	}
+}
@@ -0,0 +1,262 @@
+package autocpp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/xyproto/env"
+)
+
+// indexVersion is increased whenever the format of the index changes
+const indexVersion = 1
+
+// indexedDirectory is a directory in the index, with its modification time and entries.
+// The names of subdirectories end with "/".
+type indexedDirectory struct {
+	ModTime int64    `json:"mtime"`
+	Entries []string `json:"entries"`
+}
+
+// systemIndex is the on-disk index of the system include directories
+type systemIndex struct {
+	Version       int                         `json:"version"`
+	Key           string                      `json:"key"`
+	Directories   map[string]indexedDirectory `json:"directories"` // from slash-separated paths within the file system
+	OwnersModTime int64                       `json:"owners_mtime,omitempty"`
+	Owners        map[string]string           `json:"owners,omitempty"` // from include file to package name
+}
+
+// DefaultCacheDirectory returns $XDG_CACHE_HOME/autocpp, or ~/.cache/autocpp if XDG_CACHE_HOME is not set
+func DefaultCacheDirectory() string {
+	return filepath.Join(env.Dir("XDG_CACHE_HOME", "~/.cache"), "autocpp")
+}
+
+// indexKey returns a hash of everything that affects which include files are found
+func (locsys *LocalSystem) indexKey() string {
+	data, _ := json.Marshal(struct {
+		Sysroot     string
+		Target      string
+		Directories []string
+		Exclude     []string
+	}{locsys.sysroot, locsys.target, locsys.allSystemIncludeDirectories(), locsys.excludePatterns})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// CacheFilename returns the filename of the index, or an empty string if caching is not enabled
+func (locsys *LocalSystem) CacheFilename() string {
+	if locsys.cacheDirectory == "" {
+		return ""
+	}
+	return filepath.Join(locsys.cacheDirectory, "index-"+locsys.indexKey()[:16]+".json")
+}
+
+// InvalidateCache removes the index, so that the next LocalSystem with the same configuration
+// searches all system include directories again
+func (locsys *LocalSystem) InvalidateCache() error {
+	filename := locsys.CacheFilename()
+	if filename == "" {
+		return nil
+	}
+	if err := os.Remove(filename); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// RebuildIndex searches all system include directories again, without using the index,
+// and writes a new index if caching is enabled
+func (locsys *LocalSystem) RebuildIndex(ctx context.Context) error {
+	locsys.ownersMutex.Lock()
+	locsys.owners = nil
+	locsys.ownersMutex.Unlock()
+	if locsys.cacheDirectory == "" {
+		return locsys.scan(ctx, nil)
+	}
+	return locsys.scanWithIndex(ctx, false)
+}
+
+func (locsys *LocalSystem) readIndex() *systemIndex {
+	data, err := os.ReadFile(locsys.CacheFilename())
+	if err != nil {
+		return nil
+	}
+	var index systemIndex
+	if err := json.Unmarshal(data, &index); err != nil || index.Version != indexVersion || index.Key != locsys.indexKey() {
+		return nil
+	}
+	return &index
+}
+
+func (locsys *LocalSystem) writeIndex(index *systemIndex) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(locsys.cacheDirectory, 0o755); err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that other processes never see a partial index
+	f, err := os.CreateTemp(locsys.cacheDirectory, "index-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), locsys.CacheFilename())
+}
+
+// scanWithIndex is like scan, but only reads the directories that have been modified since the index
+// was written, and then writes a new index. If useIndex is false, all directories are read. The index
+// is only a cache, so if it can not be written, that is written to the verbose output and is not an error.
+func (locsys *LocalSystem) scanWithIndex(ctx context.Context, useIndex bool) error {
+	var oldIndex *systemIndex
+	if useIndex {
+		oldIndex = locsys.readIndex()
+	}
+	newIndex := &systemIndex{Version: indexVersion, Key: locsys.indexKey(), Directories: make(map[string]indexedDirectory)}
+	var mu sync.Mutex
+	locsys.reread = 0
+	readDir := func(dir string) ([]fs.DirEntry, error) {
+		info, err := fs.Stat(locsys.fsys, dir)
+		if err != nil {
+			return nil, err
+		}
+		modTime := info.ModTime().UnixNano()
+		if oldIndex != nil {
+			if indexed, ok := oldIndex.Directories[dir]; ok && indexed.ModTime == modTime {
+				mu.Lock()
+				newIndex.Directories[dir] = indexed
+				mu.Unlock()
+				return indexedEntries(indexed), nil
+			}
+		}
+		entries, err := fs.ReadDir(locsys.fsys, dir)
+		if err != nil {
+			return nil, err
+		}
+		indexed := indexedDirectory{ModTime: modTime, Entries: make([]string, 0, len(entries))}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				indexed.Entries = append(indexed.Entries, entry.Name()+"/")
+			} else {
+				indexed.Entries = append(indexed.Entries, entry.Name())
+			}
+		}
+		mu.Lock()
+		newIndex.Directories[dir] = indexed
+		locsys.reread++
+		mu.Unlock()
+		return entries, nil
+	}
+	if err := locsys.scan(ctx, readDir); err != nil {
+		return err
+	}
+	logf(locsys.output, "Read %d of %d directories in the system include directories\n", locsys.reread, len(newIndex.Directories))
+	// Package owners are kept in the index if the package database has not changed
+	if ownersModTime, ok := locsys.ownersModTime(); ok {
+		if oldIndex != nil && oldIndex.OwnersModTime == ownersModTime && oldIndex.Owners != nil {
+			newIndex.Owners = oldIndex.Owners
+		} else {
+			newIndex.Owners = locsys.loadOwners()
+		}
+		newIndex.OwnersModTime = ownersModTime
+		locsys.ownersMutex.Lock()
+		locsys.owners = newIndex.Owners
+		locsys.ownersMutex.Unlock()
+	}
+	if err := locsys.writeIndex(newIndex); err != nil {
+		logf(locsys.output, "could not write the index: %v\n", err)
+	}
+	return nil
+}
+
+// indexedEntries returns directory entries for the names in an indexed directory
+func indexedEntries(indexed indexedDirectory) []fs.DirEntry {
+	entries := make([]fs.DirEntry, 0, len(indexed.Entries))
+	for _, name := range indexed.Entries {
+		if strings.HasSuffix(name, "/") {
+			entries = append(entries, fs.FileInfoToDirEntry(memFileInfo{name: strings.TrimSuffix(name, "/"), dir: true}))
+		} else {
+			entries = append(entries, fs.FileInfoToDirEntry(memFileInfo{name: name}))
+		}
+	}
+	return entries
+}
+
+// PackageOwners is implemented by package systems that know which package owns which file
+type PackageOwners interface {
+	// Owners returns a map from absolute paths to package names
+	Owners() (map[string]string, error)
+	// DatabasePath returns the directory of the package database, which is modified when packages change
+	DatabasePath() string
+}
+
+// packageOwners returns the package system that has a package database within the sysroot, or nil
+func (locsys *LocalSystem) packageOwners() PackageOwners {
+	for _, packageSystem := range []PackageOwners{newPacmanFS(locsys.fsys, locsys.sysroot), newDpkgFS(locsys.fsys, locsys.sysroot)} {
+		if info, err := fs.Stat(locsys.fsys, rootedPath(packageSystem.DatabasePath())); err == nil && info.IsDir() {
+			return packageSystem
+		}
+	}
+	return nil
+}
+
+// ownersModTime returns the modification time of the package database, if there is one
+func (locsys *LocalSystem) ownersModTime() (int64, bool) {
+	packageSystem := locsys.packageOwners()
+	if packageSystem == nil {
+		return 0, false
+	}
+	info, err := fs.Stat(locsys.fsys, rootedPath(packageSystem.DatabasePath()))
+	if err != nil {
+		return 0, false
+	}
+	return info.ModTime().UnixNano(), true
+}
+
+// loadOwners returns the package owners of the include files, from the package database
+func (locsys *LocalSystem) loadOwners() map[string]string {
+	owners := make(map[string]string)
+	packageSystem := locsys.packageOwners()
+	if packageSystem == nil {
+		return owners
+	}
+	allOwners, err := packageSystem.Owners()
+	if err != nil {
+		logf(locsys.output, "%v\n", err)
+		return owners
+	}
+	for _, includeFile := range locsys.includeFiles {
+		if packageName, ok := allOwners[includeFile]; ok {
+			owners[includeFile] = packageName
+		}
+	}
+	return owners
+}
+
+// PackageOwner returns the name of the package that owns the given include file,
+// or an empty string if it is not known
+func (locsys *LocalSystem) PackageOwner(includeFile string) string {
+	locsys.ownersMutex.Lock()
+	defer locsys.ownersMutex.Unlock()
+	if locsys.owners == nil {
+		locsys.owners = locsys.loadOwners()
+	}
+	return locsys.owners[includeFile]
+}
@@ -0,0 +1,121 @@
+package autocpp
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSystemIndex(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, "usr/include/a.h", "usr/include/sub/b.h", "usr/include/other/c.h")
+	if err := os.MkdirAll(filepath.Join(root, "var/lib/pacman/local/foo-1.0-1"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "var/lib/pacman/local/foo-1.0-1/desc"), []byte("%NAME%\nfoo\n\n%VERSION%\n1.0-1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "var/lib/pacman/local/foo-1.0-1/files"), []byte("%FILES%\nusr/\nusr/include/\nusr/include/a.h\n\n%BACKUP%\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := LocalSystemOptions{
+		FS:                       os.DirFS(root),
+		IgnoreEnvironment:        true,
+		SystemIncludeDirectories: []string{"/usr/include"},
+		Cache:                    true,
+		CacheDirectory:           t.TempDir(),
+	}
+	locsys, err := NewLocalSystemWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locsys.reread != 3 {
+		t.Errorf("expected all 3 directories to be read the first time, got %d", locsys.reread)
+	}
+	if _, err := os.Stat(locsys.CacheFilename()); err != nil {
+		t.Fatalf("expected the index to be written: %v", err)
+	}
+	if owner := locsys.PackageOwner("/usr/include/a.h"); owner != "foo" {
+		t.Errorf("expected /usr/include/a.h to be owned by foo, got %q", owner)
+	}
+	first := locsys.IncludeFiles()
+
+	// Nothing has changed, so no directories should be read
+	locsys, err = NewLocalSystemWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locsys.reread != 0 {
+		t.Errorf("expected no directories to be read, got %d", locsys.reread)
+	}
+	if !reflect.DeepEqual(locsys.IncludeFiles(), first) {
+		t.Errorf("expected %v from the index, got %v", first, locsys.IncludeFiles())
+	}
+	if owner := locsys.PackageOwner("/usr/include/a.h"); owner != "foo" {
+		t.Errorf("expected the package owner to be kept in the index, got %q", owner)
+	}
+
+	// Only the modified directory should be read again
+	writeTestFiles(t, root, "usr/include/sub/d.h")
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "usr/include/sub"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	locsys, err = NewLocalSystemWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locsys.reread != 1 {
+		t.Errorf("expected 1 directory to be read, got %d", locsys.reread)
+	}
+	if !hasS(locsys.IncludeFiles(), "/usr/include/sub/d.h") {
+		t.Errorf("expected the new include file to be found, got %v", locsys.IncludeFiles())
+	}
+
+	if err := locsys.RebuildIndex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if locsys.reread != 3 {
+		t.Errorf("expected all directories to be read when rebuilding the index, got %d", locsys.reread)
+	}
+	if err := locsys.InvalidateCache(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(locsys.CacheFilename()); !os.IsNotExist(err) {
+		t.Errorf("expected the index to be removed, got %v", err)
+	}
+}
+
+func TestSystemIndexNotWritable(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, "usr/include/a.h")
+	// The cache directory can not be created, since a file is in the way
+	notADirectory := filepath.Join(t.TempDir(), "cache")
+	if err := os.WriteFile(notADirectory, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var output bytes.Buffer
+	locsys, err := NewLocalSystemWithOptions(LocalSystemOptions{
+		FS:                       os.DirFS(root),
+		IgnoreEnvironment:        true,
+		SystemIncludeDirectories: []string{"/usr/include"},
+		Cache:                    true,
+		CacheDirectory:           notADirectory,
+		Output:                   &output,
+		Verbose:                  true,
+	})
+	if err != nil {
+		t.Fatalf("expected the include files to be found without an index, got %v", err)
+	}
+	if !hasS(locsys.IncludeFiles(), "/usr/include/a.h") {
+		t.Errorf("expected /usr/include/a.h to be found, got %v", locsys.IncludeFiles())
+	}
+	if !strings.Contains(output.String(), "could not write the index") {
+		t.Errorf("expected the error to be written to the verbose output, got %q", output.String())
+	}
+}
@@ -0,0 +1,1044 @@
+// autocpp finds the includes of C and C++ projects, and the flags and build files that are needed for building them
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xyproto/autocpp"
+)
+
+const usage = `Usage: autocpp <command> [flags] [directory]
+
+Commands:
+  scan                     list the C and C++ files of the project
+  includes                 list the includes of the project
+  missing                  list the includes that can not be found, exits with 1 if there are any
+  direct                   list the standard headers that are used, but not included directly
+  unused                   list the includes whose symbols are never used, exits with 1 if there are any
+  fix                      show a diff that adds missing and removes unused includes, or apply it with --write
+  lint                     check the order, grouping and style of the includes, exits with 1 if there are issues
+  guards                   check the include guards of the headers, show fixes with --fix or apply them with --write
+  self-contained           compile each project header on its own, exits with 1 if any of them do not compile
+  cost                     estimate how much code each file pulls in, and rank the most expensive headers
+  pch                      suggest a precompiled header and its flags, or write it with --write
+  flags                    show the flags that are needed for building the project
+  include-dirs             infer the -I directories of the project headers, exits with 1 if they conflict
+  graph                    show which project files include which, and any include cycles
+  generate make|cmake|ninja  write a build file for the project
+  report                   write an analysis report as YAML, or as JSON with --json
+  which-package <include>  show which package provides an include file, like "SDL2/SDL.h"
+
+The directory is "." if not given. It can also be a .tar.gz, .tar.xz, .tar.bz2, .tar or .zip
+archive, which is read without extracting it. Every command takes --json for JSON output.
+Run "autocpp <command> -h" for the flags of a command.
+`
+
+// stringsFlag is a flag that can be given several times
+type stringsFlag []string
+
+func (sf *stringsFlag) String() string {
+	return strings.Join(*sf, ",")
+}
+
+func (sf *stringsFlag) Set(value string) error {
+	*sf = append(*sf, value)
+	return nil
+}
+
+// config holds the flags that are common to all commands
+type config struct {
+	jsonOutput      bool
+	verbose         bool
+	noCache         bool
+	ignoreGitignore bool
+	exclude         stringsFlag
+	includeFlags    stringsFlag
+	local           stringsFlag
+	noLocal         stringsFlag
+	prefer          stringsFlag
+	compiler        string
+	sysroot         string
+	target          string
+	stdout          io.Writer
+	stderr          io.Writer
+}
+
+// newFlagSet returns a flag set for the given command, with the common flags
+func newFlagSet(name string, cfg *config) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(cfg.stderr)
+	fs.BoolVar(&cfg.jsonOutput, "json", false, "write JSON")
+	fs.BoolVar(&cfg.verbose, "v", false, "verbose output, written to stderr")
+	fs.BoolVar(&cfg.noCache, "no-cache", false, "do not use the cached index of system include files")
+	fs.BoolVar(&cfg.ignoreGitignore, "no-gitignore", false, "also scan files that are ignored by .gitignore")
+	fs.Var(&cfg.exclude, "exclude", "glob pattern for files and directories to skip, can be given several times")
+	fs.Var(&cfg.includeFlags, "I", "extra include directory, can be given several times")
+	fs.Var(&cfg.local, "local", "local include directory, relative to the project directory, can be given several times")
+	fs.Var(&cfg.noLocal, "no-local", "do not search this local include directory, like .., can be given several times")
+	fs.Var(&cfg.prefer, "prefer", "prefer include files with paths that contain this, like /c++/13/, can be given several times")
+	fs.StringVar(&cfg.compiler, "compiler", "", "ask this compiler, like g++, for the system include directories")
+	fs.StringVar(&cfg.sysroot, "sysroot", "", "use the system include directories within this sysroot")
+	fs.StringVar(&cfg.target, "target", "", "target triple, like aarch64-linux-gnu")
+	return fs
+}
+
+// sources scans the given project directory
+func (cfg *config) sources(ctx context.Context, dir string) (*autocpp.Sources, error) {
+	return autocpp.NewSourcesContext(ctx, dir, autocpp.SourcesOptions{
+		Exclude:                       cfg.exclude,
+		IgnoreGitignore:               cfg.ignoreGitignore,
+		LocalIncludeDirectories:       cfg.local,
+		RemoveLocalIncludeDirectories: cfg.noLocal,
+		Output:                        cfg.stderr,
+		Verbose:                       cfg.verbose,
+	})
+}
+
+// localSystem scans the system include directories
+func (cfg *config) localSystem(ctx context.Context) (*autocpp.LocalSystem, error) {
+	var includeFlags []string
+	for _, dir := range cfg.includeFlags {
+		includeFlags = append(includeFlags, "-I"+dir)
+	}
+	var rankingPolicy autocpp.RankingPolicy
+	if len(cfg.prefer) > 0 {
+		rankingPolicy = autocpp.PreferPaths(autocpp.DefaultRankingPolicy{}, cfg.prefer...)
+	}
+	return autocpp.NewLocalSystemContext(ctx, autocpp.LocalSystemOptions{
+		RankingPolicy: rankingPolicy,
+		Compiler:      cfg.compiler,
+		Sysroot:       cfg.sysroot,
+		Target:        cfg.target,
+		IncludeFlags:  includeFlags,
+		Cache:         !cfg.noCache,
+		Output:        cfg.stderr,
+		Verbose:       cfg.verbose,
+	})
+}
+
+// writeJSON writes v as indented JSON
+func (cfg *config) writeJSON(v interface{}) error {
+	enc := json.NewEncoder(cfg.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// errUsage is returned for wrong arguments, after the usage has been written
+var errUsage = errors.New("usage")
+
+// errProblems is returned when a command succeeded, but found problems, like missing includes
+var errProblems = errors.New("problems were found")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run runs autocpp with the given arguments and returns the exit code
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	cfg := &config{stdout: stdout, stderr: stderr}
+	commands := map[string]func(context.Context, *config, []string) error{
+		"scan":           scanCommand,
+		"includes":       includesCommand,
+		"missing":        missingCommand,
+		"direct":         directCommand,
+		"unused":         unusedCommand,
+		"fix":            fixCommand,
+		"lint":           lintCommand,
+		"guards":         guardsCommand,
+		"self-contained": selfContainedCommand,
+		"cost":           costCommand,
+		"pch":            pchCommand,
+		"flags":          flagsCommand,
+		"include-dirs":   includeDirsCommand,
+		"graph":          graphCommand,
+		"generate":       generateCommand,
+		"report":         reportCommand,
+		"which-package":  whichPackageCommand,
+	}
+	name := args[0]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	}
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", name, usage)
+		return 2
+	}
+	err := command(ctx, cfg, args[1:])
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	case errors.Is(err, errProblems):
+		return 1
+	}
+	fmt.Fprintf(stderr, "autocpp %s: %v\n", name, err)
+	return 1
+}
+
+// parseArgs parses the flags and returns the other arguments. Unlike fs.Parse, flags may also come after the arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, errUsage
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// parseDirectory parses the flags and returns the project directory, which is "." if not given
+func parseDirectory(fs *flag.FlagSet, args []string) (string, error) {
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return "", err
+	}
+	switch len(positional) {
+	case 0:
+		return ".", nil
+	case 1:
+		return positional[0], nil
+	}
+	fmt.Fprintf(fs.Output(), "expected at most one directory, got: %s\n", strings.Join(positional, " "))
+	return "", errUsage
+}
+
+// relative returns the given filenames relative to the project directory
+func relative(dir string, filenames []string) []string {
+	result := make([]string, 0, len(filenames))
+	for _, filename := range filenames {
+		if rel, err := filepath.Rel(dir, filename); err == nil {
+			filename = rel
+		}
+		result = append(result, filepath.ToSlash(filename))
+	}
+	return result
+}
+
+func scanCommand(ctx context.Context, cfg *config, args []string) error {
+	dir, err := parseDirectory(newFlagSet("scan", cfg), args)
+	if err != nil {
+		return err
+	}
+	src, err := cfg.sources(ctx, dir)
+	if err != nil {
+		return err
+	}
+	var languages []string
+	for _, lang := range src.Languages() {
+		languages = append(languages, lang.String())
+	}
+	if cfg.jsonOutput {
+		return cfg.writeJSON(struct {
+			Root      string   `json:"root"`
+			Languages []string `json:"languages"`
+			Headers   []string `json:"headers"`
+			C         []string `json:"c"`
+			CPP       []string `json:"cpp"`
+		}{dir, languages, relative(dir, src.HeaderFilenames()), relative(dir, src.CFilenames()), relative(dir, src.CPPFilenames())})
+	}
+	for _, filename := range relative(dir, src.AllFilenames()) {
+		fmt.Fprintln(cfg.stdout, filename)
+	}
+	return nil
+}
+
+func includesCommand(ctx context.Context, cfg *config, args []string) error {
+	fs := newFlagSet("includes", cfg)
+	perFile := fs.Bool("files", false, "list the #include lines of every file, instead of the short include names")
+	dir, err := parseDirectory(fs, args)
+	if err != nil {
+		return err
+	}
+	src, err := cfg.sources(ctx, dir)
+	if err != nil {
+		return err
+	}
+	includes := src.Includes()
+	for i := range includes {
+		includes[i].File = relative(dir, []string{includes[i].File})[0]
+	}
+	if cfg.jsonOutput {
+		return cfg.writeJSON(struct {
+			ShortIncludes []string          `json:"short_includes"`
+			Includes      []autocpp.Include `json:"includes"`
+		}{src.ShortIncludes(), includes})
+	}
+	if *perFile {
+		for _, include := range includes {
+			fmt.Fprintf(cfg.stdout, "%s:%d: %s\n", include.File, include.Line, include)
+		}
+		return nil
+	}
+	for _, include := range src.ShortIncludes() {
+		fmt.Fprintln(cfg.stdout, include)
+	}
+	return nil
+}
+
+func missingCommand(ctx context.Context, cfg *config, args []string) error {
+	dir, err := parseDirectory(newFlagSet("missing", cfg), args)
+	if err != nil {
+		return err
+	}
+	src, err := cfg.sources(ctx, dir)
+	if err != nil {
+		return err
+	}
+	locsys, err := cfg.localSystem(ctx)
+	if err != nil {
+		return err
+	}
+	missing := src.FindIncludePaths(locsys)
+	sort.Strings(missing)
+	if cfg.jsonOutput {
+		if err := cfg.writeJSON(struct {
+			Missing []string `json:"missing"`
+		}{append([]string{}, missing...)}); err != nil {
+			return err
+		}
+	} else {
+		for _, include := range missing {
+			fmt.Fprintln(cfg.stdout, include)
+		}
+	}
+	if len(missing) > 0 {
+		return errProblems
+	}
+	return nil
+}
+
+// confidenceFlag adds the -confidence flag to the flag set
+func confidenceFlag(fs *flag.FlagSet) *string {
+	return fs.String("confidence", "medium", "only list results with at least this confidence: low, medium or high")
+}
+
+// parseConfidence parses the value of the -confidence flag
+func (cfg *config) parseConfidence(s string) (autocpp.Confidence, error) {
+	confidence, err := autocpp.ParseConfidence(s)
+	if err != nil {
+		fmt.Fprintln(cfg.stderr, err)
+		return confidence, errUsage
+	}
+	return confidence, nil
+}
+
+func directCommand(ctx context.Context, cfg *config, args []string) error {
+	fs := newFlagSet("direct", cfg)
+	minConfidence := confidenceFlag(fs)
+	dir, err := parseDirectory(fs, args)
+	if err != nil {
+		return err
+	}
+	confidence, err := cfg.parseConfidence(*minConfidence)
+	if err != nil {
+		return err
+	}
+	src, err := cfg.sources(ctx, dir)
+	if err != nil {
+		return err
+	}
+	missing := []autocpp.MissingInclude{}
+	for _, m := range src.MissingDirectIncludes() {
+		if m.Confidence >= confidence {
+			m.File = relative(dir, []string{m.File})[0]
+			if m.Through != "" {
+				m.Through = relative(dir, []string{m.Through})[0]
+			}
+			missing = append(missing, m)
+		}
+	}
+	if cfg.jsonOutput {
+		if err := cfg.writeJSON(struct {
+			Missing []autocpp.MissingInclude `json:"missing"`
+		}{missing}); err != nil {
+			return err
+		}
+	} else {
+		for _, m := range missing {
+			fmt.Fprintf(cfg.stdout, "%s:%d: add %s (%s confidence): %s\n", m.File, m.Line, m.Suggestion, m.Confidence, m.Reason)
+		}
+	}
+	if len(missing) > 0 {
+		return errProblems
+	}
+	return nil
+}
+
+func unusedCommand(ctx context.Context, cfg *config, args []string) error {
+	fs := newFlagSet("unused", cfg)
+	minConfidence := confidenceFlag(fs)
+	dir, err := parseDirectory(fs, args)
+	if err != nil {
+		return err
+	}
+	confidence, err := cfg.parseConfidence(*minConfidence)
+	if err != nil {
+		return err
+	}
+	src, err := cfg.sources(ctx, dir)
+	if err != nil {
+		return err
+	}
+	locsys, err := cfg.localSystem(ctx)
+	if err != nil {
+		return err
+	}
+	unused := []autocpp.UnusedInclude{}
+	for _, include := range src.UnusedIncludes(locsys) {
+		if include.Confidence >= confidence {
+			include.File = relative(dir, []string{include.File})[0]
+			unused = append(unused, include)
+		}
+	}
+	if cfg.jsonOutput {
+		if err := cfg.writeJSON(struct {
+			Unused []autocpp.UnusedInclude `json:"unused"`
+		}{unused}); err != nil {
+			return err
+		}
+	} else {
+		for _, include := range unused {
+			fmt.Fprintf(cfg.stdout, "%s:%d: %s is unused (%s confidence): %s\n", include.File, include.Line, include.Include, include.Confidence, include.Reason)
+		}
+	}
+	if len(unused) > 0 {
+		return errProblems
+	}
+	return nil
+}
+
+func fixCommand(ctx context.Context, cfg *config, args []string) error {
+	fs := newFlagSet("fix", cfg)
+	minConfidence := fs.String("confidence", "high", "only fix includes with at least this confidence: low, medium or high")
+	write := fs.Bool("write", false, "write the changes to the files, instead of showing a diff")
+	noAdd := fs.Bool("no-add", false, "do not add missing includes")
+	noRemove := fs.Bool("no-remove", false, "do not remove unused includes")
+	dir, err := parseDirectory(fs, args)
+	if err != nil {
+		return err
+	}
+	confidence, err := cfg.parseConfidence(*minConfidence)
+	if err != nil {
+		return err
+	}
+	src, err := cfg.sources(ctx, dir)
+	if err != nil {
+		return err
+	}
+	locsys, err := cfg.localSystem(ctx)
+	if err != nil {
+		return err
+	}
+	fixes, err := src.FixIncludes(autocpp.FixOptions{
+		MinConfidence: confidence,
+		NoAdd:         *noAdd,
+		NoRemove:      *noRemove,
+		LocalSystem:   locsys,
+		Write:         *write,
+	})
+	if err != nil {
+		return err
+	}
+	for i := range fixes {
+		fixes[i].File = relative(dir, []string{fixes[i].File})[0]
+	}
+	if cfg.jsonOutput {
+		if err := cfg.writeJSON(struct {
+			Fixes []autocpp.FileFix `json:"fixes"`
+		}{append([]autocpp.FileFix{}, fixes...)}); err != nil {
+			return err
+		}
+	} else if *write {
+		for _, fix := range fixes {
+			fmt.Fprintf(cfg.stdout, "fixed %s: %d added, %d removed\n", fix.File, len(fix.Added), len(fix.Removed))
+		}
+	} else {
+		for _, fix := range fixes {
+			fmt.Fprint(cfg.stdout, fix.Diff)
+		}
+	}
+	if len(fixes) > 0 && !*write {
+		return errProblems
+	}
+	return nil
+}
+
+func lintCommand(ctx context.Context, cfg *config, args []string) error {
+	fs := newFlagSet("lint", cfg)
+	var disabled stringsFlag
+	fs.Var(&disabled, "disable", "do not check this rule, like include-grouping, can be given several times")
+	order := fs.String("order", "", "comma-separated order of the include categories, like own-header,c-system,c++-standard,third-party,project")
+	dir, err := parseDirectory(fs, args)
+	if err != nil {
+		return err
+	}
+	for _, rule := range disabled {
+		if !hasRule(rule) {
+			fmt.Fprintf(cfg.stderr, "unknown rule %q, expected one of: %s\n", rule, strings.Join(autocpp.LintRules, ", "))
+			return errUsage
+		}
+	}
+	var categories []autocpp.IncludeCategory
+	if *order != "" {
+		for _, name := range strings.Split(*order, ",") {
+			category, err := autocpp.ParseIncludeCategory(strings.TrimSpace(name))
+			if err != nil {
+				fmt.Fprintln(cfg.stderr, err)
+				return errUsage
+			}
+			categories = append(categories, category)
+		}
+	}
+	src, err := cfg.sources(ctx, dir)
+	if err != nil {
+		return err
+	}
+	locsys, err := cfg.localSystem(ctx)
+	if err != nil {
+		return err
+	}
+	issues := append([]autocpp.LintIssue{}, src.LintIncludes(autocpp.LintOptions{
+		Disabled:    disabled,
+		Order:       categories,
+		LocalSystem: locsys,
+	})...)
+	for i := range issues {
+		issues[i].File = relative(dir, []string{issues[i].File})[0]
+	}
+	if cfg.jsonOutput {
+		if err := cfg.writeJSON(struct {
+			Issues []autocpp.LintIssue `json:"issues"`
+		}{issues}); err != nil {
+			return err
+		}
+	} else {
+		for _, issue := range issues {
+			fmt.Fprintln(cfg.stdout, issue)
+		}
+	}
+	if len(issues) > 0 {
+		return errProblems
+	}
+	return nil
+}
+
+// hasRule checks if the given name is one of the lint rules
+func hasRule(name string) bool {
+	for _, rule := range autocpp.LintRules {
+		if rule == name {
+			return true
+		}
+	}
+	return false
+}
+
+func guardsCommand(ctx context.Context, cfg *config, args []string) error {
+	fs := newFlagSet("guards", cfg)
+	fix := fs.Bool("fix", false, "show a diff that fixes the include guards")
+	write := fs.Bool("write", false, "write the fixed include guards to the files")
+	checkNames := fs.Bool("check-names", false, "also report include guards that do not follow the naming scheme")
+	var scheme autocpp.GuardScheme
+	var strip stringsFlag
+	fs.StringVar(&scheme.Prefix, "prefix", "", "prefix of new include guards, like MYPROJECT_")
+	fs.StringVar(&scheme.Suffix, "suffix", "", "suffix of new include guards, like _")
+	fs.Var(&strip, "strip", "leading directory that is not part of new include guards, like include, can be given several times")
+	fs.BoolVar(&scheme.BaseName, "basename", false, "name new include guards after the file name only")
+	dir, err := parseDirectory(fs, args)
+	if err != nil {
+		return err
+	}
+	scheme.StripDirectories = strip
+	src, err := cfg.sources(ctx, dir)
+	if err != nil {
+		return err
+	}
+	opts := autocpp.GuardOptions{Scheme: scheme, CheckNames: *checkNames, Write: *write}
+	if !*fix && !*write {
+		issues := append([]autocpp.GuardIssue{}, src.CheckHeaderGuards(opts)...)
+		for i := range issues {
+			issues[i].File = relative(dir, []string{issues[i].File})[0]
+			issues[i].Others = relative(dir, issues[i].Others)
+		}
+		if cfg.jsonOutput {
+			if err := cfg.writeJSON(struct {
+				Issues []autocpp.GuardIssue `json:"issues"`
+			}{issues}); err != nil {
+				return err
+			}
+		} else {
+			for _, issue := range issues {
+				fmt.Fprintln(cfg.stdout, issue)
+			}
+		}
+		if len(issues) > 0 {
+			return errProblems
+		}
+		return nil
+	}
+	fixes, err := src.FixHeaderGuards(opts)
+	if err != nil {
+		return err
+	}
+	for i := range fixes {
+		fixes[i].File = relative(dir, []string{fixes[i].File})[0]
+	}
+	if cfg.jsonOutput {
+		if err := cfg.writeJSON(struct {
+			Fixes []autocpp.GuardFix `json:"fixes"`
+		}{append([]autocpp.GuardFix{}, fixes...)}); err != nil {
+			return err
+		}
+	} else if *write {
+		for _, fix := range fixes {
+			fmt.Fprintf(cfg.stdout, "fixed %s: %s\n", fix.File, fix.Guard)
+		}
+	} else {
+		for _, fix := range fixes {
+			fmt.Fprint(cfg.stdout, fix.Diff)
+		}
+	}
+	if len(fixes) > 0 && !*write {
+		return errProblems
+	}
+	return nil
+}
+
+func selfContainedCommand(ctx context.Context, cfg *config, args []string) error {
+	fs := newFlagSet("self-contained", cfg)
+	var extraFlags stringsFlag
+	fs.Var(&extraFlags, "flag", "extra compiler flag, like -std=c++20, can be given several times")
+	dir, err := parseDirectory(fs, args)
+	if err != nil {
+		return err
+	}
+	src, err := cfg.sources(ctx, dir)
+	if err != nil {
+		return err
+	}
+	locsys, err := cfg.localSystem(ctx)
+	if err != nil {
+		return err
+	}
+	failures, err := src.CheckSelfContainedHeaders(ctx, autocpp.SelfContainedOptions{
+		Compiler:    autocpp.ExecCompiler{Command: cfg.compiler},
+		LocalSystem: locsys,
+		Flags:       extraFlags,
+	})
+	if err != nil {
+		return err
+	}
+	failures = append([]autocpp.HeaderCompileError{}, failures...)
+	for i := range failures {
+		failures[i].Header = relative(dir, []string{failures[i].Header})[0]
+	}
+	if cfg.jsonOutput {
+		if err := cfg.writeJSON(struct {
+			Failures []autocpp.HeaderCompileError `json:"failures"`
+		}{failures}); err != nil {
+			return err
+		}
+	} else {
+		for _, failure := range failures {
+			fmt.Fprintf(cfg.stdout, "%s does not compile on its own as %s:\n%s", failure.Header, failure.Language, failure.Output)
+		}
+	}
+	if len(failures) > 0 {
+		return errProblems
+	}
+	return nil
+}
+
+func costCommand(ctx context.Context, cfg *config, args []string) error {
+	fs := newFlagSet("cost", cfg)
+	top := fs.Int("top", 10, "the number of translation units and headers to list, or 0 for all")
+	dir, err := parseDirectory(fs, args)
+	if err != nil {
+		return err
+	}
+	src, err := cfg.sources(ctx, dir)
+	if err != nil {
+		return err
+	}
+	locsys, err := cfg.localSystem(ctx)
+	if err != nil {
+		return err
+	}
+	cost := src.EstimateCompileCost(locsys)
+	for i := range cost.TranslationUnits {
+		cost.TranslationUnits[i].File = relative(dir, []string{cost.TranslationUnits[i].File})[0]
+	}
+	for i := range cost.Headers {
+		if cost.Headers[i].Project {
+			cost.Headers[i].Header = relative(dir, []string{cost.Headers[i].Header})[0]
+		}
+	}
+	for i := range cost.ForwardDeclarations {
+		cost.ForwardDeclarations[i].File = relative(dir, []string{cost.ForwardDeclarations[i].File})[0]
+		cost.ForwardDeclarations[i].Header = relative(dir, []string{cost.ForwardDeclarations[i].Header})[0]
+	}
+	if *top > 0 {
+		if len(cost.TranslationUnits) > *top {
+			cost.TranslationUnits = cost.TranslationUnits[:*top]
+		}
+		if len(cost.Headers) > *top {
+			cost.Headers = cost.Headers[:*top]
+		}
+	}
+	if cfg.jsonOutput {
+		return cfg.writeJSON(cost)
+	}
+	fmt.Fprintf(cfg.stdout, "Total: %s, %d lines\n", formatBytes(cost.Bytes), cost.Lines)
+	fmt.Fprintln(cfg.stdout, "\nTranslation units:")
+	for _, unit := range cost.TranslationUnits {
+		fmt.Fprintf(cfg.stdout, "  %s: %s, %d lines, %d headers", unit.File, formatBytes(unit.Bytes), unit.Lines, unit.Headers)
+		if len(unit.Unresolved) > 0 {
+			fmt.Fprintf(cfg.stdout, ", %d not found", len(unit.Unresolved))
+		}
+		fmt.Fprintln(cfg.stdout)
+	}
+	fmt.Fprintln(cfg.stdout, "\nMost expensive headers:")
+	for _, header := range cost.Headers {
+		name := "<" + header.Name + ">"
+		if header.Project {
+			name = `"` + header.Name + `"`
+		}
+		fmt.Fprintf(cfg.stdout, "  %s: %s x %d = %s", name, formatBytes(header.Bytes), header.TranslationUnits, formatBytes(header.TotalBytes))
+		if header.PCHCandidate {
+			fmt.Fprint(cfg.stdout, " (precompiled header candidate)")
+		}
+		fmt.Fprintln(cfg.stdout)
+	}
+	if len(cost.ForwardDeclarations) > 0 {
+		fmt.Fprintln(cfg.stdout, "\nForward declaration candidates:")
+		for _, fd := range cost.ForwardDeclarations {
+			fmt.Fprintf(cfg.stdout, "  %s:%d: %s can be replaced with %s\n", fd.File, fd.Line, fd.Include, strings.Join(fd.Declarations, " "))
+		}
+	}
+	return nil
+}
+
+// formatBytes formats a number of bytes, like "1.5 MiB"
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+func pchCommand(ctx context.Context, cfg *config, args []string) error {
+	fs := newFlagSet("pch", cfg)
+	filename := fs.String("o", autocpp.DefaultPCHFilename, "the name of the precompiled header, relative to the project directory")
+	minFrequency := fs.Float64("min-frequency", 0.5, "the fraction of translation units that must include a header")
+	maxHeaders := fs.Int("max", 0, "the maximum number of headers, or 0 for no limit")
+	write := fs.Bool("write", false, "write the precompiled header to the project directory")
+	dir, err := parseDirectory(fs, args)
+	if err != nil {
+		return err
+	}
+	if *minFrequency <= 0 || *minFrequency > 1 {
+		fmt.Fprintln(cfg.stderr, "the minimum frequency must be larger than 0 and at most 1")
+		return errUsage
+	}
+	src, err := cfg.sources(ctx, dir)
+	if err != nil {
+		return err
+	}
+	locsys, err := cfg.localSystem(ctx)
+	if err != nil {
+		return err
+	}
+	pch, err := src.SuggestPrecompiledHeader(autocpp.PCHOptions{
+		MinFrequency: *minFrequency,
+		MaxHeaders:   *maxHeaders,
+		LocalSystem:  locsys,
+		Filename:     *filename,
+		Write:        *write,
+	})
+	if err != nil {
+		return err
+	}
+	compileFlags := src.BuildFlags(locsys).CompileFlags()
+	if cfg.jsonOutput {
+		return cfg.writeJSON(struct {
+			*autocpp.PrecompiledHeader
+			Content      string           `json:"content"`
+			CompileFlags []string         `json:"compile_flags"`
+			GCC          autocpp.PCHFlags `json:"gcc"`
+			Clang        autocpp.PCHFlags `json:"clang"`
+		}{pch, pch.Content(), append([]string{}, compileFlags...), pch.GCCFlags(), pch.ClangFlags()})
+	}
+	if len(pch.Headers) == 0 {
+		fmt.Fprintf(cfg.stdout, "no system headers are included by enough of the %d translation units\n", pch.TranslationUnits)
+		return nil
+	}
+	if *write {
+		fmt.Fprintf(cfg.stdout, "wrote %s\n", filepath.Join(dir, pch.Filename))
+	} else {
+		fmt.Fprint(cfg.stdout, pch.Content())
+	}
+	gcc, clang := "g++", "clang++"
+	if pch.Language == autocpp.LanguageC {
+		gcc, clang = "gcc", "clang"
+	}
+	for _, compiler := range []struct {
+		name  string
+		flags autocpp.PCHFlags
+	}{{gcc, pch.GCCFlags()}, {clang, pch.ClangFlags()}} {
+		fmt.Fprintf(cfg.stdout, "\n%s:\n  build: %s\n  use:   %s\n", compiler.name,
+			strings.Join(append(append([]string{compiler.name}, compileFlags...), compiler.flags.Build...), " "), strings.Join(compiler.flags.Use, " "))
+	}
+	return nil
+}
+
+func flagsCommand(ctx context.Context, cfg *config, args []string) error {
+	dir, err := parseDirectory(newFlagSet("flags", cfg), args)
+	if err != nil {
+		return err
+	}
+	src, err := cfg.sources(ctx, dir)
+	if err != nil {
+		return err
+	}
+	locsys, err := cfg.localSystem(ctx)
+	if err != nil {
+		return err
+	}
+	flags := src.BuildFlags(locsys)
+	if cfg.jsonOutput {
+		return cfg.writeJSON(struct {
+			autocpp.BuildFlags
+			CompileFlags []string `json:"compile_flags"`
+			LinkerFlags  []string `json:"linker_flags"`
+		}{flags, append([]string{}, flags.CompileFlags()...), append([]string{}, flags.LinkerFlags()...)})
+	}
+	fmt.Fprintln(cfg.stdout, strings.Join(append(flags.CompileFlags(), flags.LinkerFlags()...), " "))
+	return nil
+}
+
+func includeDirsCommand(ctx context.Context, cfg *config, args []string) error {
+	dir, err := parseDirectory(newFlagSet("include-dirs", cfg), args)
+	if err != nil {
+		return err
+	}
+	src, err := cfg.sources(ctx, dir)
+	if err != nil {
+		return err
+	}
+	locsys, err := cfg.localSystem(ctx)
+	if err != nil {
+		return err
+	}
+	inference := src.InferIncludeDirectories(locsys)
+	if cfg.jsonOutput {
+		if err := cfg.writeJSON(inference); err != nil {
+			return err
+		}
+	} else {
+		for _, includeDirectory := range inference.Directories {
+			fmt.Fprintln(cfg.stdout, "-I"+includeDirectory)
+		}
+		for _, ambiguity := range inference.Ambiguities {
+			fmt.Fprintln(cfg.stderr, "ambiguous: "+ambiguity)
+		}
+		for _, conflict := range inference.Conflicts {
+			fmt.Fprintln(cfg.stderr, "conflict: "+conflict)
+		}
+	}
+	if len(inference.Conflicts) > 0 {
+		return errProblems
+	}
+	return nil
+}
+
+func graphCommand(ctx context.Context, cfg *config, args []string) error {
+	dir, err := parseDirectory(newFlagSet("graph", cfg), args)
+	if err != nil {
+		return err
+	}
+	src, err := cfg.sources(ctx, dir)
+	if err != nil {
+		return err
+	}
+	graph := make(map[string][]string)
+	for filename, includedFiles := range src.IncludeGraph() {
+		graph[relative(dir, []string{filename})[0]] = relative(dir, includedFiles)
+	}
+	cycles := [][]string{}
+	for _, cycle := range src.Cycles() {
+		cycles = append(cycles, relative(dir, cycle))
+	}
+	if cfg.jsonOutput {
+		return cfg.writeJSON(struct {
+			Graph  map[string][]string `json:"graph"`
+			Cycles [][]string          `json:"cycles"`
+		}{graph, cycles})
+	}
+	filenames := make([]string, 0, len(graph))
+	for filename := range graph {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	for _, filename := range filenames {
+		for _, includedFile := range graph[filename] {
+			fmt.Fprintf(cfg.stdout, "%s -> %s\n", filename, includedFile)
+		}
+	}
+	for _, cycle := range cycles {
+		fmt.Fprintf(cfg.stdout, "cycle: %s\n", strings.Join(cycle, ", "))
+	}
+	return nil
+}
+
+func generateCommand(ctx context.Context, cfg *config, args []string) error {
+	fs := newFlagSet("generate", cfg)
+	output := fs.String("o", "", "write the build file to this file, instead of to stdout")
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintf(cfg.stderr, "expected a build system: %s\n", strings.Join(autocpp.Generators, ", "))
+		return errUsage
+	}
+	buildSystem := args[0]
+	dir, err := parseDirectory(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	src, err := cfg.sources(ctx, dir)
+	if err != nil {
+		return err
+	}
+	locsys, err := cfg.localSystem(ctx)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := src.Generate(&buf, buildSystem, src.BuildFlags(locsys)); err != nil {
+		return err
+	}
+	if *output != "" {
+		if err := os.WriteFile(*output, buf.Bytes(), 0o644); err != nil {
+			return err
+		}
+	}
+	if cfg.jsonOutput {
+		return cfg.writeJSON(struct {
+			BuildSystem string `json:"build_system"`
+			Filename    string `json:"filename,omitempty"`
+			Content     string `json:"content"`
+		}{buildSystem, *output, buf.String()})
+	}
+	if *output == "" {
+		_, err = cfg.stdout.Write(buf.Bytes())
+	}
+	return err
+}
+
+func reportCommand(ctx context.Context, cfg *config, args []string) error {
+	dir, err := parseDirectory(newFlagSet("report", cfg), args)
+	if err != nil {
+		return err
+	}
+	src, err := cfg.sources(ctx, dir)
+	if err != nil {
+		return err
+	}
+	locsys, err := cfg.localSystem(ctx)
+	if err != nil {
+		return err
+	}
+	report := src.Report(locsys)
+	if cfg.jsonOutput {
+		return report.WriteJSON(cfg.stdout)
+	}
+	return report.WriteYAML(cfg.stdout)
+}
+
+func whichPackageCommand(ctx context.Context, cfg *config, args []string) error {
+	fs := newFlagSet("which-package", cfg)
+	includes, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(includes) == 0 {
+		fmt.Fprintln(cfg.stderr, "expected one or more includes, like SDL2/SDL.h")
+		return errUsage
+	}
+	locsys, err := cfg.localSystem(ctx)
+	if err != nil {
+		return err
+	}
+	type result struct {
+		Include string `json:"include"`
+		Path    string `json:"path,omitempty"`
+		Package string `json:"package,omitempty"`
+	}
+	var (
+		results  []result
+		notFound bool
+	)
+	for _, include := range includes {
+		r := result{Include: include}
+		if path, ok := locsys.FindIncludeFile(include); ok {
+			r.Path = path
+			r.Package = locsys.PackageOwner(path)
+		}
+		if r.Package == "" {
+			notFound = true
+		}
+		results = append(results, r)
+	}
+	if cfg.jsonOutput {
+		if err := cfg.writeJSON(results); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			switch {
+			case r.Package != "":
+				fmt.Fprintf(cfg.stdout, "%s: %s (%s)\n", r.Include, r.Package, r.Path)
+			case r.Path != "":
+				fmt.Fprintf(cfg.stdout, "%s: no package (%s)\n", r.Include, r.Path)
+			default:
+				fmt.Fprintf(cfg.stdout, "%s: not found\n", r.Include)
+			}
+		}
+	}
+	if notFound {
+		return errProblems
+	}
+	return nil
+}
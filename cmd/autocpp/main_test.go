@@ -0,0 +1,277 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const exampleProjectDirectory = "../../testdata/fireworks"
+
+func runForTest(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, &stdout, &stderr)
+	if code == 2 {
+		t.Errorf("usage error for %v: %s", args, stderr.String())
+	}
+	return stdout.String(), code
+}
+
+func TestScan(t *testing.T) {
+	output, code := runForTest(t, "scan", exampleProjectDirectory, "--json", "--exclude", "build")
+	if code != 0 {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+	var result struct {
+		Headers []string `json:"headers"`
+		CPP     []string `json:"cpp"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Headers, []string{"fireworks.h", "include/particle.h"}) || !reflect.DeepEqual(result.CPP, []string{"main.cpp"}) {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestIncludesAndGraph(t *testing.T) {
+	output, _ := runForTest(t, "includes", exampleProjectDirectory)
+	if !strings.Contains(output, "SDL2/SDL.h\n") || !strings.Contains(output, "particle.h\n") {
+		t.Errorf("unexpected includes: %s", output)
+	}
+	output, _ = runForTest(t, "includes", "--files", exampleProjectDirectory)
+	if !strings.Contains(output, "main.cpp:5: #include <SDL2/SDL.h>\n") {
+		t.Errorf("unexpected includes: %s", output)
+	}
+	output, _ = runForTest(t, "graph", exampleProjectDirectory)
+	if output != "fireworks.h -> include/particle.h\nmain.cpp -> fireworks.h\n" {
+		t.Errorf("unexpected graph: %q", output)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, contents := range map[string]string{"libfoo-1.0/src/foo.cpp": "#include <vector>\n", "libfoo-1.0/README": "#include <ignored>\n"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "libfoo-1.0.zip")
+	if err := os.WriteFile(filename, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if output, code := runForTest(t, "includes", "--files", filename); code != 0 || output != "libfoo-1.0/src/foo.cpp:1: #include <vector>\n" {
+		t.Errorf("unexpected includes with exit code %d: %q", code, output)
+	}
+}
+
+func TestMissing(t *testing.T) {
+	output, code := runForTest(t, "missing", "--no-cache", "--json", exampleProjectDirectory)
+	var result struct {
+		Missing []string `json:"missing"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatal(err)
+	}
+	if !hasS(result.Missing, "generated_by_cmake.h") || hasS(result.Missing, "particle.h") {
+		t.Errorf("unexpected missing includes: %v", result.Missing)
+	}
+	if code != 1 {
+		t.Errorf("expected exit code 1 when includes are missing, got %d", code)
+	}
+}
+
+func TestGenerateCommand(t *testing.T) {
+	output, code := runForTest(t, "generate", "ninja", "--no-cache", exampleProjectDirectory)
+	if code != 0 || !strings.Contains(output, "build fireworks: link build/generated.cpp.o main.cpp.o\n") {
+		t.Errorf("unexpected output with exit code %d: %s", code, output)
+	}
+	if _, code := runForTest(t, "generate", "scons", "--no-cache", exampleProjectDirectory); code != 1 {
+		t.Errorf("expected exit code 1 for an unknown build system, got %d", code)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"frobnicate"}, &stdout, &stderr); code != 2 {
+		t.Errorf("expected exit code 2 for an unknown command, got %d", code)
+	}
+	if code := run(context.Background(), []string{"scan", "a", "b"}, &stdout, &stderr); code != 2 {
+		t.Errorf("expected exit code 2 for two directories, got %d", code)
+	}
+	if code := run(context.Background(), []string{"unused", "--confidence", "certain"}, &stdout, &stderr); code != 2 {
+		t.Errorf("expected exit code 2 for an unknown confidence level, got %d", code)
+	}
+	if code := run(context.Background(), []string{"lint", "--disable", "no-such-rule"}, &stdout, &stderr); code != 2 {
+		t.Errorf("expected exit code 2 for an unknown lint rule, got %d", code)
+	}
+	if code := run(context.Background(), []string{"lint", "--order", "c-system,standard"}, &stdout, &stderr); code != 2 {
+		t.Errorf("expected exit code 2 for an unknown include category, got %d", code)
+	}
+	if code := run(context.Background(), []string{"pch", "--min-frequency", "2"}, &stdout, &stderr); code != 2 {
+		t.Errorf("expected exit code 2 for a frequency above 1, got %d", code)
+	}
+}
+
+func hasS(xs []string, e string) bool {
+	for _, x := range xs {
+		if x == e {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIncludeDirs(t *testing.T) {
+	output, code := runForTest(t, "include-dirs", "--no-cache", exampleProjectDirectory)
+	if code != 0 || output != "-Iinclude\n" {
+		t.Errorf("unexpected output with exit code %d: %q", code, output)
+	}
+}
+
+func TestDirect(t *testing.T) {
+	output, code := runForTest(t, "direct", "--json", exampleProjectDirectory)
+	if code != 0 || !strings.Contains(output, `"missing": []`) {
+		t.Errorf("unexpected output with exit code %d: %s", code, output)
+	}
+}
+
+func TestFix(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.cpp"), []byte("#include <string>\n\nint main() { std::vector<int> v; }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output, code := runForTest(t, "fix", "--no-cache", dir)
+	if code != 1 || !strings.Contains(output, "+#include <vector>\n") {
+		t.Errorf("unexpected output with exit code %d: %s", code, output)
+	}
+	if _, code := runForTest(t, "fix", "--no-cache", "--write", dir); code != 0 {
+		t.Errorf("unexpected exit code %d", code)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "main.cpp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "#include <string>\n#include <vector>\n\n") {
+		t.Errorf("unexpected contents after fixing:\n%s", data)
+	}
+}
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.cpp"), []byte("#include <vector>\n#include <stdio.h>\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output, code := runForTest(t, "lint", "--no-cache", "--disable", "include-grouping", dir)
+	expected := "main.cpp:2: stdio.h is a deprecated C header in C++, use <cstdio> [deprecated-c-header]\n" +
+		"main.cpp:2: stdio.h (c-system) should come before the c++-standard includes [include-order]\n"
+	if code != 1 || output != expected {
+		t.Errorf("unexpected output with exit code %d: %s", code, output)
+	}
+	if _, code := runForTest(t, "lint", "--no-cache", "--order", "c++-standard,c-system", "--disable", "deprecated-c-header", "--disable", "include-grouping", dir); code != 0 {
+		t.Errorf("expected exit code 0 with a custom order, got %d", code)
+	}
+}
+
+func TestGuards(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "util.h"), []byte("int util(void);\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output, code := runForTest(t, "guards", dir)
+	if code != 1 || output != "util.h:1: no include guard or #pragma once [missing]\n" {
+		t.Errorf("unexpected output with exit code %d: %s", code, output)
+	}
+	output, code = runForTest(t, "guards", "--write", "--prefix", "MY_", dir)
+	if code != 0 || output != "fixed util.h: MY_UTIL_H\n" {
+		t.Errorf("unexpected output with exit code %d: %s", code, output)
+	}
+	if _, code := runForTest(t, "guards", exampleProjectDirectory); code != 0 {
+		t.Errorf("expected the include guards of the example project to be fine, got exit code %d", code)
+	}
+}
+
+func TestSelfContained(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake compiler is a shell script")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "util.h"), []byte("size_t count;\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// The fake compiler can not be asked for its include directories, and fails for every header
+	compiler := filepath.Join(t.TempDir(), "cc")
+	script := "#!/bin/sh\ncase \"$*\" in *-fsyntax-only*) echo \"util.h:1:1: error\" >&2 ;; esac\nexit 1\n"
+	if err := os.WriteFile(compiler, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	output, code := runForTest(t, "self-contained", "--no-cache", "--compiler", compiler, dir)
+	if code != 1 || output != "util.h does not compile on its own as c++:\nutil.h:1:1: error\n" {
+		t.Errorf("unexpected output with exit code %d: %s", code, output)
+	}
+}
+
+func TestCost(t *testing.T) {
+	output, code := runForTest(t, "cost", "--no-cache", "--json", exampleProjectDirectory)
+	if code != 0 {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+	var result struct {
+		TranslationUnits []struct {
+			File  string `json:"file"`
+			Bytes int64  `json:"bytes"`
+		} `json:"translation_units"`
+		Headers []struct {
+			Name string `json:"name"`
+		} `json:"headers"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.TranslationUnits) == 0 || result.TranslationUnits[0].File != "main.cpp" || result.TranslationUnits[0].Bytes == 0 {
+		t.Errorf("unexpected translation units: %+v", result.TranslationUnits)
+	}
+	found := false
+	for _, header := range result.Headers {
+		found = found || header.Name == "fireworks.h"
+	}
+	if !found {
+		t.Errorf("expected fireworks.h among the headers: %+v", result.Headers)
+	}
+}
+
+func TestPCH(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.cpp", "b.cpp"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#include <vector>\n#include <string>\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	output, code := runForTest(t, "pch", "--no-cache", "--write", dir)
+	if code != 0 || !strings.Contains(output, "g++:\n  build: g++ -x c++-header pch.hpp -o pch.hpp.gch\n  use:   -include pch.hpp -Winvalid-pch\n") {
+		t.Errorf("unexpected output with exit code %d: %s", code, output)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "pch.hpp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(data), "\n#include <string>\n#include <vector>\n") {
+		t.Errorf("unexpected precompiled header:\n%s", data)
+	}
+}
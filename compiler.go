@@ -0,0 +1,95 @@
+package autocpp
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// CompilerSearchPaths is the include search path, as reported by a compiler
+type CompilerSearchPaths struct {
+	Quote []string // only searched for #include "..."
+	Angle []string // searched for both #include <...> and #include "..."
+}
+
+// ParseCompilerSearchPaths parses the output of "gcc -E -x c++ - -v" or "clang -E -x c++ - -v"
+// and returns the directories listed after "#include "..." search starts here:" and
+// "#include <...> search starts here:", in the order the compiler searches them.
+func ParseCompilerSearchPaths(output string) CompilerSearchPaths {
+	var (
+		searchPaths CompilerSearchPaths
+		current     *[]string
+	)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmedLine := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmedLine, "#include \"...\" search starts here"):
+			current = &searchPaths.Quote
+			continue
+		case strings.HasPrefix(trimmedLine, "#include <...> search starts here"):
+			current = &searchPaths.Angle
+			continue
+		case strings.HasPrefix(trimmedLine, "End of search list"):
+			current = nil
+			continue
+		}
+		// The directories are indented by a single space
+		if current == nil || trimmedLine == "" || !strings.HasPrefix(line, " ") {
+			continue
+		}
+		// clang on macOS marks some directories as framework directories
+		trimmedLine = strings.TrimSuffix(trimmedLine, " (framework directory)")
+		*current = append(*current, filepath.Clean(trimmedLine))
+	}
+	return searchPaths
+}
+
+// QueryCompilerSearchPaths runs the given compiler (like "g++" or "clang++") on an empty C++
+// translation unit and returns the include search path that the compiler reports.
+// Any extra arguments (like "--sysroot=..." or "--target=...") are passed on to the compiler.
+func QueryCompilerSearchPaths(compiler string, args ...string) (CompilerSearchPaths, error) {
+	return QueryCompilerSearchPathsFor(compiler, LanguageCXX, args...)
+}
+
+// QueryCompilerSearchPathsFor is like QueryCompilerSearchPaths, but for an empty translation unit
+// in the given language, since C++ adds directories like /usr/include/c++/12 to the search path
+func QueryCompilerSearchPathsFor(compiler string, lang Language, args ...string) (CompilerSearchPaths, error) {
+	language := "c++"
+	if lang == LanguageC {
+		language = "c"
+	}
+	var output bytes.Buffer
+	cmd := exec.Command(compiler, append([]string{"-E", "-x", language, "-", "-v"}, args...)...)
+	cmd.Stdin = strings.NewReader("")
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return CompilerSearchPaths{}, fmt.Errorf("could not query %s for the %s include search path: %w", compiler, lang, err)
+	}
+	searchPaths := ParseCompilerSearchPaths(output.String())
+	if len(searchPaths.Angle) == 0 {
+		return searchPaths, fmt.Errorf("found no %s include search path in the output of %s", lang, compiler)
+	}
+	return searchPaths, nil
+}
+
+// splitSearchPaths returns the directories that are searched for both C and C++, in the order of the
+// C++ search path, and the directories that are only searched for C or only for C++
+func splitSearchPaths(c, cxx []string) (common, cOnly, cxxOnly []string) {
+	for _, dir := range cxx {
+		if hasS(c, dir) {
+			common = append(common, dir)
+		} else {
+			cxxOnly = append(cxxOnly, dir)
+		}
+	}
+	for _, dir := range c {
+		if !hasS(cxx, dir) {
+			cOnly = append(cOnly, dir)
+		}
+	}
+	return common, cOnly, cxxOnly
+}
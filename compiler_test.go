@@ -0,0 +1,140 @@
+package autocpp
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"testing/fstest"
+)
+
+const gccSearchPathOutput = `Using built-in specs.
+COLLECT_GCC=gcc
+Target: x86_64-linux-gnu
+Thread model: posix
+gcc version 12.2.0 (Debian 12.2.0-14+deb12u1)
+COLLECT_GCC_OPTIONS='-E' '-v' '-mtune=generic' '-march=x86-64'
+ /usr/lib/gcc/x86_64-linux-gnu/12/cc1plus -E -quiet -v -imultiarch x86_64-linux-gnu -D_GNU_SOURCE - -mtune=generic -march=x86-64 -fasynchronous-unwind-tables -dumpbase -
+ignoring duplicate directory "/usr/include/x86_64-linux-gnu/c++/12"
+ignoring nonexistent directory "/usr/local/include/x86_64-linux-gnu"
+ignoring nonexistent directory "/usr/lib/gcc/x86_64-linux-gnu/12/include-fixed"
+#include "..." search starts here:
+#include <...> search starts here:
+ /usr/include/c++/12
+ /usr/include/x86_64-linux-gnu/c++/12
+ /usr/include/c++/12/backward
+ /usr/lib/gcc/x86_64-linux-gnu/12/include
+ /usr/local/include
+ /usr/include/x86_64-linux-gnu
+ /usr/include
+End of search list.
+COMPILER_PATH=/usr/lib/gcc/x86_64-linux-gnu/12/:/usr/lib/gcc/x86_64-linux-gnu/
+LIBRARY_PATH=/usr/lib/gcc/x86_64-linux-gnu/12/:/lib/x86_64-linux-gnu/:/usr/lib/
+COLLECT_GCC_OPTIONS='-E' '-v' '-mtune=generic' '-march=x86-64'
+`
+
+const clangSearchPathOutput = `clang version 16.0.6
+Target: x86_64-pc-linux-gnu
+Thread model: posix
+InstalledDir: /usr/bin
+Found candidate GCC installation: /usr/bin/../lib/gcc/x86_64-linux-gnu/12
+Selected GCC installation: /usr/bin/../lib/gcc/x86_64-linux-gnu/12
+ "/usr/lib/llvm-16/bin/clang" -cc1 -triple x86_64-pc-linux-gnu -E -disable-free -x c++ -
+clang -cc1 version 16.0.6 based upon LLVM 16.0.6 default target x86_64-pc-linux-gnu
+ignoring nonexistent directory "/include"
+#include "..." search starts here:
+ /home/user/project/include
+#include <...> search starts here:
+ /usr/bin/../lib/gcc/x86_64-linux-gnu/12/../../../../include/c++/12
+ /usr/bin/../lib/gcc/x86_64-linux-gnu/12/../../../../include/x86_64-linux-gnu/c++/12
+ /usr/lib/llvm-16/lib/clang/16/include
+ /usr/local/include
+ /usr/include
+ /System/Library/Frameworks (framework directory)
+End of search list.
+`
+
+func TestParseCompilerSearchPathsGCC(t *testing.T) {
+	searchPaths := ParseCompilerSearchPaths(gccSearchPathOutput)
+	if len(searchPaths.Quote) != 0 {
+		t.Errorf("expected no quote directories, got %v", searchPaths.Quote)
+	}
+	expected := []string{
+		"/usr/include/c++/12",
+		"/usr/include/x86_64-linux-gnu/c++/12",
+		"/usr/include/c++/12/backward",
+		"/usr/lib/gcc/x86_64-linux-gnu/12/include",
+		"/usr/local/include",
+		"/usr/include/x86_64-linux-gnu",
+		"/usr/include",
+	}
+	if !reflect.DeepEqual(searchPaths.Angle, expected) {
+		t.Errorf("expected %v, got %v", expected, searchPaths.Angle)
+	}
+}
+
+func TestParseCompilerSearchPathsClang(t *testing.T) {
+	searchPaths := ParseCompilerSearchPaths(clangSearchPathOutput)
+	if !reflect.DeepEqual(searchPaths.Quote, []string{"/home/user/project/include"}) {
+		t.Errorf("unexpected quote directories: %v", searchPaths.Quote)
+	}
+	expected := []string{
+		"/usr/include/c++/12",
+		"/usr/include/x86_64-linux-gnu/c++/12",
+		"/usr/lib/llvm-16/lib/clang/16/include",
+		"/usr/local/include",
+		"/usr/include",
+		"/System/Library/Frameworks",
+	}
+	if !reflect.DeepEqual(searchPaths.Angle, expected) {
+		t.Errorf("expected %v, got %v", expected, searchPaths.Angle)
+	}
+}
+
+func TestParseCompilerSearchPathsEmpty(t *testing.T) {
+	searchPaths := ParseCompilerSearchPaths("g++: error: unrecognized command-line option\n")
+	if len(searchPaths.Quote) != 0 || len(searchPaths.Angle) != 0 {
+		t.Errorf("expected no directories, got %v", searchPaths)
+	}
+}
+
+func TestCompilerSearchPathsPerLanguage(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake compiler is a shell script")
+	}
+	// A fake compiler that reports /usr/include/c++/12 only for C++
+	compiler := filepath.Join(t.TempDir(), "cc")
+	script := `#!/bin/sh
+echo '#include <...> search starts here:' >&2
+if [ "$3" = "c++" ]; then
+	echo ' /usr/include/c++/12' >&2
+else
+	echo ' /usr/lib/gcc/include-c' >&2
+fi
+echo ' /usr/include' >&2
+echo 'End of search list.' >&2
+`
+	if err := os.WriteFile(compiler, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	c, err := QueryCompilerSearchPathsFor(compiler, LanguageC)
+	if err != nil || !reflect.DeepEqual(c.Angle, []string{"/usr/lib/gcc/include-c", "/usr/include"}) {
+		t.Fatalf("unexpected C search path %v: %v", c.Angle, err)
+	}
+	fsys := fstest.MapFS{
+		"usr/include/stdio.h":       {Data: []byte("\n")},
+		"usr/include/c++/12/vector": {Data: []byte("\n")},
+		"usr/lib/gcc/include-c/x.h": {Data: []byte("\n")},
+	}
+	locsys, err := NewLocalSystemWithOptions(LocalSystemOptions{FS: fsys, Compiler: compiler, IgnoreEnvironment: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if searchPath := locsys.SearchPath(LanguageC); !reflect.DeepEqual(searchPath, []string{"/usr/lib/gcc/include-c", "/usr/include"}) {
+		t.Errorf("unexpected C search path: %v", searchPath)
+	}
+	if searchPath := locsys.SearchPath(LanguageCXX); !reflect.DeepEqual(searchPath, []string{"/usr/include/c++/12", "/usr/include"}) {
+		t.Errorf("unexpected C++ search path: %v", searchPath)
+	}
+}
@@ -0,0 +1,324 @@
+package autocpp
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// TranslationUnitCost is the estimated size of a C or C++ file after preprocessing
+type TranslationUnitCost struct {
+	File string `json:"file"`
+	// Bytes and Lines are the sizes of the file and of all the headers that it includes, directly or indirectly
+	Bytes int64 `json:"bytes"`
+	Lines int   `json:"lines"`
+	// Headers is the number of headers that the file includes, directly or indirectly
+	Headers int `json:"headers"`
+	// Unresolved are the includes that could not be found, so the estimate is too low
+	Unresolved []string `json:"unresolved,omitempty"`
+}
+
+// HeaderCost is the estimated cost of a header that is included by a project file
+type HeaderCost struct {
+	// Header is the path of the header, and Name is how it is included, like "SDL2/SDL.h"
+	Header  string `json:"header"`
+	Name    string `json:"name"`
+	Project bool   `json:"project"`
+	// Bytes and Lines are the sizes of the header and of all the headers that it includes
+	Bytes int64 `json:"bytes"`
+	Lines int   `json:"lines"`
+	// Headers is the number of headers that the header includes, directly or indirectly
+	Headers int `json:"headers"`
+	// TranslationUnits is the number of C and C++ files that include the header, directly or indirectly
+	TranslationUnits int `json:"translation_units"`
+	// TotalBytes is Bytes times TranslationUnits, which is how much the header adds to a full build
+	// if the translation units do not share the headers that it includes
+	TotalBytes int64 `json:"total_bytes"`
+	// PCHCandidate is true for headers that are not part of the project, and that are included by
+	// at least half of the translation units
+	PCHCandidate bool `json:"pch_candidate"`
+}
+
+// ForwardDeclaration is an #include line in a project header that could be replaced by forward declarations,
+// because the header only uses the classes of the included header through pointers and references
+type ForwardDeclaration struct {
+	Include
+	// Header is the path of the included header
+	Header string `json:"header"`
+	// Declarations are the forward declarations that are needed instead, like "class Renderer;"
+	Declarations []string `json:"declarations"`
+}
+
+// CompileCost is an estimate of how much code the compiler has to read, found by EstimateCompileCost
+type CompileCost struct {
+	// Bytes and Lines are the sums for all translation units
+	Bytes int64 `json:"bytes"`
+	Lines int   `json:"lines"`
+	// TranslationUnits are ordered by size, the largest first
+	TranslationUnits []TranslationUnitCost `json:"translation_units"`
+	// Headers are the headers that project files include, ordered by total bytes, the most expensive first
+	Headers             []HeaderCost         `json:"headers"`
+	ForwardDeclarations []ForwardDeclaration `json:"forward_declarations"`
+}
+
+// countLines returns the number of lines in data, also counting a last line without a newline
+func countLines(data []byte) int {
+	lines := bytes.Count(data, []byte("\n"))
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		lines++
+	}
+	return lines
+}
+
+// closure returns the headers that the given file includes, directly or indirectly, and the include
+// names that could not be resolved. Every header is counted once, as if it has an include guard.
+func (index *symbolIndex) closure(path string) (headers []string, unresolved []string) {
+	visited := map[string]bool{path: true}
+	queue := []string{path}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		h := index.header(current)
+		if !h.ok {
+			continue
+		}
+		for _, include := range h.includes {
+			target, ok := index.resolve(include)
+			if !ok {
+				unresolved = appendUnique(unresolved, include.Name)
+				continue
+			}
+			if !visited[target] {
+				visited[target] = true
+				headers = append(headers, target)
+				queue = append(queue, target)
+			}
+		}
+	}
+	return headers, unresolved
+}
+
+// size returns the sum of the sizes of the given headers
+func (index *symbolIndex) size(headers []string) (int64, int) {
+	var size int64
+	var lines int
+	for _, header := range headers {
+		h := index.header(header)
+		size += h.size
+		lines += h.lines
+	}
+	return size, lines
+}
+
+// EstimateCompileCost estimates how many bytes and lines each C and C++ file of the project pulls in, by following
+// the includes through the project and system headers and adding up their sizes. It also ranks the headers that
+// project files include by how much they cost in a full build, and finds includes in project headers that could
+// be replaced by forward declarations. This does not run the preprocessor, so #if blocks are not taken into account.
+// locsys may be nil, and then only the project headers are counted.
+func (src *Sources) EstimateCompileCost(locsys *LocalSystem) *CompileCost {
+	if locsys != nil {
+		src.FindIncludePaths(locsys)
+	}
+	index := newSymbolIndex(src, locsys)
+	index.noSymbols = true
+	cost := &CompileCost{TranslationUnits: []TranslationUnitCost{}, Headers: []HeaderCost{}, ForwardDeclarations: []ForwardDeclaration{}}
+	filenames := append(append([]string{}, src.absFilenamesC...), src.absFilenamesCPP...)
+	sort.Strings(filenames)
+	closures := make(map[string]map[string]bool)
+	for _, filename := range filenames {
+		headers, unresolved := index.closure(filename)
+		closures[filename] = make(map[string]bool)
+		for _, header := range headers {
+			closures[filename][header] = true
+		}
+		size, lines := index.size(append(headers, filename))
+		cost.TranslationUnits = append(cost.TranslationUnits, TranslationUnitCost{File: filename, Bytes: size, Lines: lines, Headers: len(headers), Unresolved: unresolved})
+		cost.Bytes += size
+		cost.Lines += lines
+	}
+	sort.SliceStable(cost.TranslationUnits, func(i, j int) bool {
+		return cost.TranslationUnits[i].Bytes > cost.TranslationUnits[j].Bytes
+	})
+
+	// The headers that are included by project files
+	names := make(map[string]string)
+	var included []string
+	for _, filename := range src.AllFilenames() {
+		for _, include := range src.FileIncludes(filename) {
+			if target, ok := index.resolve(include); ok {
+				if _, seen := names[target]; !seen {
+					names[target] = include.Name
+					included = append(included, target)
+				}
+			}
+		}
+	}
+	for _, header := range included {
+		headers, _ := index.closure(header)
+		size, lines := index.size(append(headers, header))
+		_, project := src.files[header]
+		headerCost := HeaderCost{Header: header, Name: names[header], Project: project, Bytes: size, Lines: lines, Headers: len(headers)}
+		for _, filename := range filenames {
+			if closures[filename][header] {
+				headerCost.TranslationUnits++
+			}
+		}
+		headerCost.TotalBytes = headerCost.Bytes * int64(headerCost.TranslationUnits)
+		headerCost.PCHCandidate = !project && headerCost.TranslationUnits >= 2 && headerCost.TranslationUnits*2 >= len(filenames)
+		cost.Headers = append(cost.Headers, headerCost)
+	}
+	sort.SliceStable(cost.Headers, func(i, j int) bool {
+		a, b := cost.Headers[i], cost.Headers[j]
+		if a.TotalBytes != b.TotalBytes {
+			return a.TotalBytes > b.TotalBytes
+		}
+		return a.Header < b.Header
+	})
+	cost.ForwardDeclarations = append(cost.ForwardDeclarations, src.forwardDeclarations(locsys)...)
+	return cost
+}
+
+// forwardDeclarations finds the includes of project headers in project headers, where the including header only
+// uses classes of the included header, and only through pointers and references
+func (src *Sources) forwardDeclarations(locsys *LocalSystem) []ForwardDeclaration {
+	index := newSymbolIndex(src, locsys)
+	var candidates []ForwardDeclaration
+	for _, filename := range src.absFilenamesHeader {
+		f, ok := src.files[filename]
+		if !ok || len(f.includes) == 0 {
+			continue
+		}
+		tokens := tokenize(codeWithoutDirectives(f.data))
+		conditional := conditionalLines(f.data)
+		for _, include := range f.includes {
+			header, ok := src.ResolveProjectInclude(include)
+			if !ok || conditional[include.Line] || !isHeaderFile(header) {
+				continue
+			}
+			declarable := classDeclarations(src.files[header].data)
+			names, _ := index.symbols(header)
+			var declarations []string
+			for _, token := range tokens {
+				if keyword, ok := declarable[token]; ok {
+					declarations = appendUnique(declarations, keyword+" "+token+";")
+				}
+			}
+			if len(declarations) == 0 || !onlyThroughPointers(tokens, declarable, names) {
+				continue
+			}
+			sort.Strings(declarations)
+			candidates = append(candidates, ForwardDeclaration{Include: include, Header: header, Declarations: declarations})
+		}
+	}
+	return candidates
+}
+
+// codeWithoutDirectives returns the code without comments and without preprocessor directives
+func codeWithoutDirectives(data []byte) string {
+	var sb strings.Builder
+	for _, line := range logicalLines(stripComments(data)) {
+		if _, _, ok := directive(line); !ok {
+			sb.WriteString(line)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// classDeclarations returns the classes and structs that the header defines at the top level, and that can be
+// forward declared, mapped to "class" or "struct". Templates, enums and classes within namespaces are skipped.
+func classDeclarations(data []byte) map[string]string {
+	declarable := make(map[string]string)
+	tokens := tokenize(codeWithoutDirectives(data))
+	depth := 0
+	for i, token := range tokens {
+		switch token {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		case "class", "struct":
+			if depth != 0 || i+2 >= len(tokens) || !isIdentifier(tokens[i+1]) || cppKeywords[tokens[i+1]] {
+				continue
+			}
+			if i > 0 && (tokens[i-1] == ">" || tokens[i-1] == "enum") {
+				continue
+			}
+			if next := tokens[i+2]; next == "{" || next == ":" || next == "final" {
+				declarable[tokens[i+1]] = token
+			}
+		}
+	}
+	return declarable
+}
+
+// functionBodySpecifiers are the tokens that can come between the parameters of a function and its body
+var functionBodySpecifiers = map[string]bool{")": true, "const": true, "override": true, "final": true, "noexcept": true}
+
+// onlyThroughPointers checks that the tokens only use the given symbols as classes through pointers and references,
+// or in forward declarations, and that none of the other symbols are used. The members of the classes must not be
+// accessed or deleted through the pointers and references, and the classes must not be used in inline function
+// bodies, since that needs the complete class.
+func onlyThroughPointers(tokens []string, declarable map[string]string, symbols map[string]bool) bool {
+	// The names of the pointers and references to the classes
+	variables := make(map[string]bool)
+	for i, token := range tokens {
+		if _, ok := declarable[token]; !ok {
+			continue
+		}
+		next := i + 1
+		for next < len(tokens) && (tokens[next] == "*" || tokens[next] == "&" || tokens[next] == "const") {
+			next++
+		}
+		if next < len(tokens) && isIdentifier(tokens[next]) && !cppKeywords[tokens[next]] {
+			variables[tokens[next]] = true
+		}
+	}
+	var functionBodies []bool // for each open brace, if it starts a function body
+	depth := 0                // the number of function bodies that the token is in
+	for i, token := range tokens {
+		switch token {
+		case "{":
+			body := i > 0 && functionBodySpecifiers[tokens[i-1]]
+			functionBodies = append(functionBodies, body)
+			if body {
+				depth++
+			}
+			continue
+		case "}":
+			if n := len(functionBodies); n > 0 {
+				if functionBodies[n-1] {
+					depth--
+				}
+				functionBodies = functionBodies[:n-1]
+			}
+			continue
+		}
+		if variables[token] && i+1 < len(tokens) && (tokens[i+1] == "." || (tokens[i+1] == "-" && i+2 < len(tokens) && tokens[i+2] == ">")) {
+			return false
+		}
+		if variables[token] && i > 0 && (tokens[i-1] == "delete" || (tokens[i-1] == "]" && i >= 3 && tokens[i-3] == "delete")) {
+			return false
+		}
+		if _, ok := declarable[token]; !ok {
+			if symbols[token] {
+				return false
+			}
+			continue
+		}
+		if depth > 0 {
+			return false
+		}
+		if i > 0 && (tokens[i-1] == "class" || tokens[i-1] == "struct" || tokens[i-1] == "::") {
+			continue
+		}
+		next := i + 1
+		for next < len(tokens) && tokens[next] == "const" {
+			next++
+		}
+		if next >= len(tokens) || (tokens[next] != "*" && tokens[next] != "&") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package autocpp
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEstimateCompileCost(t *testing.T) {
+	const (
+		game     = "#pragma once\n#include \"renderer.h\"\n#include \"world.h\"\nclass Game {\n\tRenderer *renderer;\n\tWorld world;\n};\n"
+		renderer = "#pragma once\nclass Renderer {\npublic:\n\tvoid draw();\n};\n"
+		world    = "#pragma once\nstruct World { int size; };\n"
+	)
+	fsys := fstest.MapFS{
+		"main.cpp":   {Data: []byte("#include \"game.h\"\n#include <vector>\nint main() {}\n")},
+		"other.cpp":  {Data: []byte("#include <vector>\n#include \"renderer.h\"")},
+		"game.h":     {Data: []byte(game)},
+		"renderer.h": {Data: []byte(renderer)},
+		"world.h":    {Data: []byte(world)},
+	}
+	src, err := NewSourcesWithOptions("project", SourcesOptions{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cost := src.EstimateCompileCost(newTestSystem(t))
+	if len(cost.TranslationUnits) != 2 || cost.TranslationUnits[0].File != "project/main.cpp" || len(cost.TranslationUnits[0].Unresolved) != 0 {
+		t.Fatalf("unexpected translation units: %+v", cost.TranslationUnits)
+	}
+	headers := make(map[string]HeaderCost)
+	for _, header := range cost.Headers {
+		headers[header.Name] = header
+	}
+	vector := headers["vector"]
+	if !vector.PCHCandidate || vector.Project || vector.TranslationUnits != 2 || vector.TotalBytes != 2*vector.Bytes {
+		t.Errorf("expected vector to be a PCH candidate: %+v", vector)
+	}
+	if cost.Headers[0].Name != "game.h" || cost.Headers[1].Name != "renderer.h" {
+		t.Errorf("expected the headers to be ordered by total bytes: %+v", cost.Headers)
+	}
+	if r := headers["renderer.h"]; !r.Project || r.PCHCandidate || r.TranslationUnits != 2 || r.Bytes != int64(len(renderer)) || r.Lines != 5 || r.Headers != 0 {
+		t.Errorf("unexpected cost of renderer.h: %+v", r)
+	}
+	if g := headers["game.h"]; g.Bytes != int64(len(game)+len(renderer)+len(world)) || g.Headers != 2 || g.TranslationUnits != 1 {
+		t.Errorf("unexpected cost of game.h: %+v", g)
+	}
+	main := cost.TranslationUnits[0]
+	if main.Bytes != int64(len(fsys["main.cpp"].Data)+len(game)+len(renderer)+len(world))+vector.Bytes || main.Headers != 3+vector.Headers+1 {
+		t.Errorf("unexpected cost of main.cpp: %+v", main)
+	}
+	if other := cost.TranslationUnits[1]; other.Lines != 2+5+vector.Lines {
+		t.Errorf("unexpected number of lines for other.cpp: %+v", other)
+	}
+	if cost.Bytes != cost.TranslationUnits[0].Bytes+cost.TranslationUnits[1].Bytes {
+		t.Errorf("unexpected total: %d", cost.Bytes)
+	}
+	// Game only uses Renderer through a pointer, but World by value
+	expected := []ForwardDeclaration{{
+		Include:      Include{File: "project/game.h", Line: 2, Name: "renderer.h"},
+		Header:       "project/renderer.h",
+		Declarations: []string{"class Renderer;"},
+	}}
+	if !reflect.DeepEqual(cost.ForwardDeclarations, expected) {
+		t.Errorf("unexpected forward declarations: %+v", cost.ForwardDeclarations)
+	}
+}
+
+func TestForwardDeclarationsWithMemberAccess(t *testing.T) {
+	renderer := "#pragma once\nclass Renderer {\npublic:\n\tvoid draw();\n\tint width;\n};\n"
+	for name, game := range map[string]string{
+		"arrow":  "#pragma once\n#include \"renderer.h\"\nclass Game {\n\tRenderer* r;\n\tvoid tick() { r->draw(); }\n\tint w() const { return r->width; }\n};\n",
+		"dot":    "#pragma once\n#include \"renderer.h\"\ninline int width(const Renderer& r) { return r.width; }\n",
+		"cast":   "#pragma once\n#include \"renderer.h\"\ninline void draw(void* p) { static_cast<Renderer*>(p)->draw(); }\n",
+		"delete": "#pragma once\n#include \"renderer.h\"\ninline void destroy(Renderer* r) { delete r; }\n",
+	} {
+		fsys := fstest.MapFS{
+			"main.cpp":   {Data: []byte("#include \"game.h\"\n")},
+			"game.h":     {Data: []byte(game)},
+			"renderer.h": {Data: []byte(renderer)},
+		}
+		src, err := NewSourcesWithOptions("project", SourcesOptions{FS: fsys})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if declarations := src.EstimateCompileCost(nil).ForwardDeclarations; len(declarations) != 0 {
+			t.Errorf("%s: expected no forward declarations, since the class must be complete, got %+v", name, declarations)
+		}
+	}
+}
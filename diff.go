@@ -0,0 +1,93 @@
+package autocpp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines around the changes in a unified diff
+const diffContext = 3
+
+// diffLine is a line in an edit script, with kind ' ' for unchanged lines, '-' for removed lines
+// and '+' for added lines
+type diffLine struct {
+	kind byte
+	text string
+}
+
+// unifiedDiff returns the edit script as a unified diff between a/name and b/name,
+// or "" if nothing changed
+func unifiedDiff(name string, script []diffLine) string {
+	var sb strings.Builder
+	oldLine, newLine := 1, 1 // the line numbers of script[i], in the old and the new file
+	for i := 0; i < len(script); {
+		if script[i].kind == ' ' {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+		// A hunk starts up to diffContext lines before the change, and ends when there are more than
+		// 2*diffContext unchanged lines in a row, or at the end of the script
+		start := i
+		for start > 0 && i-start < diffContext && script[start-1].kind == ' ' {
+			start--
+		}
+		end := i
+		for unchanged := 0; end < len(script) && unchanged <= 2*diffContext; end++ {
+			if script[end].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		// Trim the trailing unchanged lines down to diffContext
+		for end > i && script[end-1].kind == ' ' {
+			end--
+		}
+		for trailing := 0; end < len(script) && trailing < diffContext && script[end].kind == ' '; trailing++ {
+			end++
+		}
+		oldStart, newStart := oldLine-(i-start), newLine-(i-start)
+		oldCount, newCount := 0, 0
+		for _, line := range script[start:end] {
+			if line.kind != '+' {
+				oldCount++
+			}
+			if line.kind != '-' {
+				newCount++
+			}
+		}
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- a/%s\n+++ b/%s\n", name, name)
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+		for _, line := range script[start:end] {
+			sb.WriteByte(line.kind)
+			sb.WriteString(line.text)
+			sb.WriteByte('\n')
+		}
+		for _, line := range script[i:end] {
+			if line.kind != '+' {
+				oldLine++
+			}
+			if line.kind != '-' {
+				newLine++
+			}
+		}
+		i = end
+	}
+	return sb.String()
+}
+
+// hunkRange formats the start and the number of lines of a hunk, like "3,7". Like for GNU diff,
+// an empty range starts at the line before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,219 @@
+package autocpp
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MissingInclude is a standard header that a file uses symbols from, without including it directly
+type MissingInclude struct {
+	File string `json:"file"`
+	// Line is the first line that uses one of the symbols
+	Line int `json:"line"`
+	// Header is the canonical header for the symbols, like "vector" or "cstdio"
+	Header  string   `json:"header"`
+	Symbols []string `json:"symbols"`
+	// Through is the project file that includes the header, if the file gets it through a project include
+	Through    string     `json:"through,omitempty"`
+	Confidence Confidence `json:"confidence"`
+	Reason     string     `json:"reason"`
+	// Suggestion is the #include line that should be added, like "#include <vector>"
+	Suggestion string `json:"suggestion"`
+}
+
+// fileLanguage returns the language of a source file. Headers with a .h extension are C++ headers if the
+// project has any C++ source files, or if there are no C source files.
+func (src *Sources) fileLanguage(filename string) Language {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".c":
+		return LanguageC
+	case ".h":
+		return src.Languages()[0]
+	}
+	return LanguageCXX
+}
+
+// impliedStandardHeaders are the headers that the C++ standard guarantees that a standard header includes
+var impliedStandardHeaders = map[string][]string{
+	"iostream":      {"ios", "streambuf", "istream", "ostream"},
+	"ios":           {"iosfwd"},
+	"bitset":        {"string", "iosfwd"},
+	"regex":         {"initializer_list", "string"},
+	"random":        {"initializer_list"},
+	"utility":       {"initializer_list"},
+	"array":         {"initializer_list"},
+	"deque":         {"initializer_list"},
+	"forward_list":  {"initializer_list"},
+	"list":          {"initializer_list"},
+	"map":           {"initializer_list"},
+	"set":           {"initializer_list"},
+	"string":        {"initializer_list"},
+	"unordered_map": {"initializer_list"},
+	"unordered_set": {"initializer_list"},
+	"valarray":      {"initializer_list"},
+	"vector":        {"initializer_list"},
+}
+
+// includesHeader checks if the given includes have the header, or the C or C++ version of it,
+// like stdio.h for cstdio, or a standard header that is guaranteed to include it, like iostream for ostream
+func includesHeader(includes []Include, header string) bool {
+	for _, include := range includes {
+		if include.Name == header || cxxHeadersForC[include.Name] == header || cxxHeadersForC[header] == include.Name || hasS(impliedStandardHeaders[include.Name], header) {
+			return true
+		}
+	}
+	return false
+}
+
+// usesWithoutCall checks if a C symbol can be used without calling it, like types, macros and stdout.
+// Other C symbols, like "time" or "free", are only counted when they are called, since they are
+// also common variable names.
+func usesWithoutCall(symbol string) bool {
+	return strings.ToLower(symbol) != symbol || strings.HasSuffix(symbol, "_t") || cMacros[symbol] || symbol == "va_list" || symbol == "jmp_buf"
+}
+
+// standardSymbolUse is a use of a standard symbol in a file
+type standardSymbolUse struct {
+	symbol     string // like "std::vector" or "printf"
+	line       int
+	confidence Confidence
+}
+
+// standardSymbolUses finds the uses of the well-known standard symbols in the given source code.
+// Symbols that the file declares itself are skipped.
+func standardSymbolUses(data []byte) []standardSymbolUse {
+	var (
+		uses     []standardSymbolUse
+		usingStd bool
+		lines    = logicalLines(stripComments(data))
+		tokens   = make([][]string, len(lines))
+		declared = make(map[string]bool)
+	)
+	for _, name := range ParseHeaderSymbols(data).Names() {
+		declared[name] = true
+	}
+	for i, line := range lines {
+		if name, _, ok := directive(line); ok && (name == "include" || name == "include_next") {
+			continue
+		}
+		tokens[i] = tokenize(line)
+		for j := 0; j+3 < len(tokens[i]); j++ {
+			if tokens[i][j] == "using" && tokens[i][j+1] == "namespace" && tokens[i][j+2] == "std" && tokens[i][j+3] == ";" {
+				usingStd = true
+			}
+		}
+	}
+	for i, lineTokens := range tokens {
+		for j := 0; j < len(lineTokens); j++ {
+			token := lineTokens[j]
+			if token == "std" && j+2 < len(lineTokens) && lineTokens[j+1] == "::" {
+				if symbol := "std::" + lineTokens[j+2]; len(standardHeadersFor(symbol)) > 0 {
+					uses = append(uses, standardSymbolUse{symbol: symbol, line: i + 1, confidence: ConfidenceHigh})
+				}
+				j += 2
+				continue
+			}
+			if !isIdentifier(token) || declared[token] {
+				continue
+			}
+			if j > 0 && (lineTokens[j-1] == "." || lineTokens[j-1] == "::" || (j > 1 && lineTokens[j-2] == "-" && lineTokens[j-1] == ">")) {
+				// A member or a name in another namespace
+				continue
+			}
+			called := j+1 < len(lineTokens) && lineTokens[j+1] == "("
+			if len(standardHeadersFor(token)) > 0 && (called || usesWithoutCall(token)) {
+				uses = append(uses, standardSymbolUse{symbol: token, line: i + 1, confidence: ConfidenceHigh})
+			} else if usingStd && len(standardHeadersFor("std::"+token)) > 0 {
+				// With "using namespace std", "vector" could be std::vector, but it could also be something else
+				uses = append(uses, standardSymbolUse{symbol: "std::" + token, line: i + 1, confidence: ConfidenceMedium})
+			}
+		}
+	}
+	return uses
+}
+
+// MissingDirectIncludes finds files that use well-known standard symbols, like std::vector, printf or
+// uint32_t, without directly including the header that declares them. The file may still compile, because
+// the header is included through another header, but that can change when the other header changes.
+// For C++ files, the C++ version of a C header is suggested, like cstdio for printf.
+// The results are ordered by file and then by line.
+func (src *Sources) MissingDirectIncludes() []MissingInclude {
+	var missing []MissingInclude
+	for _, filename := range src.AllFilenames() {
+		f, ok := src.files[filename]
+		if !ok {
+			continue
+		}
+		lang := src.fileLanguage(filename)
+		byHeader := make(map[string]*MissingInclude)
+		for _, use := range standardSymbolUses(f.data) {
+			headers := standardHeadersFor(use.symbol)
+			satisfied := false
+			for _, header := range headers {
+				if includesHeader(f.includes, header) {
+					satisfied = true
+					break
+				}
+			}
+			if satisfied {
+				continue
+			}
+			header, _ := StandardHeaderFor(use.symbol, lang)
+			m, ok := byHeader[header]
+			if !ok {
+				m = &MissingInclude{File: filename, Line: use.line, Header: header, Confidence: use.confidence}
+				byHeader[header] = m
+			}
+			m.Symbols = appendUnique(m.Symbols, use.symbol)
+			if use.confidence > m.Confidence {
+				m.Confidence = use.confidence
+			}
+		}
+		for _, m := range byHeader {
+			sort.Strings(m.Symbols)
+			m.Suggestion = Include{Name: m.Header, System: true}.String()
+			m.Through = src.includedThrough(filename, m.Header)
+			if m.Through != "" {
+				m.Reason = fmt.Sprintf("%s is used, but <%s> is only included through %s", strings.Join(m.Symbols, ", "), m.Header, m.Through)
+			} else {
+				m.Reason = fmt.Sprintf("%s is used, but <%s> is not included directly", strings.Join(m.Symbols, ", "), m.Header)
+			}
+			missing = append(missing, *m)
+		}
+	}
+	sort.SliceStable(missing, func(i, j int) bool {
+		if missing[i].File != missing[j].File {
+			return missing[i].File < missing[j].File
+		}
+		if missing[i].Line != missing[j].Line {
+			return missing[i].Line < missing[j].Line
+		}
+		return missing[i].Header < missing[j].Header
+	})
+	return missing
+}
+
+// includedThrough returns the first project file, in breadth-first order, that the given file includes
+// directly or indirectly and that includes the header directly, or "" if there is none
+func (src *Sources) includedThrough(filename, header string) string {
+	visited := map[string]bool{filename: true}
+	queue := []string{filename}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, include := range src.FileIncludes(current) {
+			target, ok := src.ResolveProjectInclude(include)
+			if !ok || visited[target] {
+				continue
+			}
+			if includesHeader(src.FileIncludes(target), header) {
+				return target
+			}
+			visited[target] = true
+			queue = append(queue, target)
+		}
+	}
+	return ""
+}
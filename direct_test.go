@@ -0,0 +1,86 @@
+package autocpp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStandardHeaderFor(t *testing.T) {
+	for _, test := range []struct {
+		symbol string
+		lang   Language
+		header string
+	}{
+		{"std::vector", LanguageCXX, "vector"},
+		{"printf", LanguageC, "stdio.h"},
+		{"printf", LanguageCXX, "cstdio"},
+		{"std::printf", LanguageCXX, "cstdio"},
+		{"uint32_t", LanguageCXX, "cstdint"},
+		{"size_t", LanguageC, "stddef.h"},
+		{"std::unique_ptr", LanguageCXX, "memory"},
+		{"std::move", LanguageCXX, "utility"},
+		{"std::swap", LanguageCXX, "utility"},
+	} {
+		if header, ok := StandardHeaderFor(test.symbol, test.lang); !ok || header != test.header {
+			t.Errorf("expected %s for %s, got %q", test.header, test.symbol, header)
+		}
+	}
+	if _, ok := StandardHeaderFor("std::frobnicate", LanguageCXX); ok {
+		t.Errorf("did not expect a header for an unknown symbol")
+	}
+	// The table builds on the catalog of common includes
+	common := (&LocalSystem{}).CommonIncludes()
+	for _, entry := range standardHeaderSymbols {
+		if !hasS(common, entry.header) {
+			t.Errorf("%s is not one of the common includes", entry.header)
+		}
+	}
+	for _, cxxHeader := range cxxHeadersForC {
+		if !hasS(common, cxxHeader) {
+			t.Errorf("%s is not one of the common includes", cxxHeader)
+		}
+	}
+}
+
+func TestMissingDirectIncludes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.cpp": {Data: []byte(`#include "widget.h"
+#include <string>
+#include <cstdint>
+
+int main() {
+	std::vector<std::string> names; // std::map is only mentioned in a comment
+	uint32_t count = 0;
+	printf("%d\n", count);
+	auto p = std::make_unique<int>(1);
+	float time = 0; // not a call to time()
+	return 0;
+}
+`)},
+		"widget.h": {Data: []byte("#pragma once\n#include <vector>\n")},
+		"util.cpp": {Data: []byte("#include <stdio.h>\nusing namespace std;\nint log(int x);\nvoid f() { map<int, int> m; printf(\"\"); log(2); }\n")},
+		"move.cpp": {Data: []byte("#include <string>\nstd::string f(std::string s) { return std::move(s); }\n")},
+		"alloc.c":  {Data: []byte("#include <stdio.h>\nvoid *g(void) { return malloc(1); }\n")},
+	}
+	src, err := NewSourcesWithOptions("project", SourcesOptions{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []string
+	for _, m := range src.MissingDirectIncludes() {
+		results = append(results, strings.Join([]string{m.File, m.Suggestion, strings.Join(m.Symbols, ","), m.Through, m.Confidence.String()}, "|"))
+	}
+	expected := []string{
+		"project/alloc.c|#include <stdlib.h>|malloc||high",
+		"project/main.cpp|#include <vector>|std::vector|project/widget.h|high",
+		"project/main.cpp|#include <cstdio>|printf||high",
+		"project/main.cpp|#include <memory>|std::make_unique||high",
+		"project/move.cpp|#include <utility>|std::move||high",
+		"project/util.cpp|#include <map>|std::map||medium",
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("unexpected missing includes:\n%s", strings.Join(results, "\n"))
+	}
+}
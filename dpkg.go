@@ -0,0 +1,66 @@
+package autocpp
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Dpkg can find the owners of installed files on Debian-based systems
+type Dpkg struct {
+	fsys   fs.FS  // rooted at "/"
+	root   string // the root directory that the packages are installed in
+	dbPath string // the dpkg database, typically /var/lib/dpkg
+}
+
+// NewDpkg returns a Dpkg that uses the package database within the given root directory.
+// An empty root directory means "/".
+func NewDpkg(rootDirectory string) *Dpkg {
+	return newDpkgFS(os.DirFS("/"), rootDirectory)
+}
+
+// newDpkgFS is like NewDpkg, but reads the package database from the given file system, rooted at "/"
+func newDpkgFS(fsys fs.FS, rootDirectory string) *Dpkg {
+	if rootDirectory == "" {
+		rootDirectory = "/"
+	}
+	return &Dpkg{fsys: fsys, root: rootDirectory, dbPath: filepath.Join(rootDirectory, "var", "lib", "dpkg")}
+}
+
+// DatabasePath returns the directory with the lists of installed files
+func (dpkg *Dpkg) DatabasePath() string {
+	return filepath.Join(dpkg.dbPath, "info")
+}
+
+// Owners returns a map from the absolute paths of all installed files to the names of the packages that own them
+func (dpkg *Dpkg) Owners() (map[string]string, error) {
+	infoDir := rootedPath(dpkg.DatabasePath())
+	entries, err := fs.ReadDir(dpkg.fsys, infoDir)
+	if err != nil {
+		return nil, err
+	}
+	owners := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".list") {
+			continue
+		}
+		// The filename may contain the architecture, like "libsdl2-dev:amd64.list"
+		packageName := strings.TrimSuffix(entry.Name(), ".list")
+		if i := strings.Index(packageName, ":"); i >= 0 {
+			packageName = packageName[:i]
+		}
+		data, err := fs.ReadFile(dpkg.fsys, path.Join(infoDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		for _, filename := range strings.Split(string(data), "\n") {
+			if filename = strings.TrimSpace(filename); filename == "" || filename == "/." {
+				continue
+			}
+			owners[filepath.Join(dpkg.root, filepath.FromSlash(filename))] = packageName
+		}
+	}
+	return owners, nil
+}
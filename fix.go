@@ -0,0 +1,395 @@
+package autocpp
+
+import (
+	"errors"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// IncludeCategory is the kind of an #include line, in the order that includes are usually grouped in
+type IncludeCategory int
+
+const (
+	// CategoryOwnHeader is the header of a source file, like widget.h for widget.cpp
+	CategoryOwnHeader IncludeCategory = iota
+	// CategoryCSystem is a C standard header, like <stdio.h>
+	CategoryCSystem
+	// CategoryCXXStandard is a C++ standard header, like <vector> or <cstdio>
+	CategoryCXXStandard
+	// CategoryThirdParty is any other <...> include, like <SDL2/SDL.h>
+	CategoryThirdParty
+	// CategoryProject is a "..." include
+	CategoryProject
+)
+
+var includeCategoryNames = []string{
+	CategoryOwnHeader:   "own-header",
+	CategoryCSystem:     "c-system",
+	CategoryCXXStandard: "c++-standard",
+	CategoryThirdParty:  "third-party",
+	CategoryProject:     "project",
+}
+
+// String returns a name like "c-system"
+func (category IncludeCategory) String() string {
+	if category < 0 || int(category) >= len(includeCategoryNames) {
+		return "unknown"
+	}
+	return includeCategoryNames[category]
+}
+
+// MarshalText makes the category appear by name in JSON
+func (category IncludeCategory) MarshalText() ([]byte, error) {
+	return []byte(category.String()), nil
+}
+
+// isCXXStandardHeader checks if the given include is a C++ standard header, like "vector" or "cstdio"
+func isCXXStandardHeader(name string) bool {
+	if _, ok := cxxStandardHeaders[name]; ok {
+		return true
+	}
+	for _, cxxHeader := range cxxHeadersForC {
+		if cxxHeader == name {
+			return true
+		}
+	}
+	for _, entry := range standardHeaderSymbols {
+		if entry.header == name && path.Ext(name) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// isCSystemHeader checks if the given include is a C standard header, like "stdio.h"
+func isCSystemHeader(name string) bool {
+	if _, ok := cxxHeadersForC[name]; ok {
+		return true
+	}
+	_, ok := cStandardHeaders[name]
+	return ok
+}
+
+// CategoryOf returns the category of an #include line in the given file
+func CategoryOf(include Include) IncludeCategory {
+	switch {
+	case isOwnHeader(include.File, include.Name) && include.File != "" && !isHeaderFile(include.File):
+		return CategoryOwnHeader
+	case !include.System:
+		return CategoryProject
+	case isCSystemHeader(include.Name):
+		return CategoryCSystem
+	case isCXXStandardHeader(include.Name):
+		return CategoryCXXStandard
+	}
+	return CategoryThirdParty
+}
+
+// isHeaderFile checks if the filename has a header extension, like .h or .hpp
+func isHeaderFile(filename string) bool {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".h", ".hh", ".hpp", ".hxx", ".h++", ".inl":
+		return true
+	}
+	return false
+}
+
+// FixOptions can be used for configuring FixIncludes
+type FixOptions struct {
+	// MinConfidence is the lowest confidence of the diagnostics that are fixed. Since ConfidenceLow is
+	// the zero value, set this to ConfidenceHigh to only make the safest fixes.
+	MinConfidence Confidence
+	// NoAdd and NoRemove turn off adding missing includes and removing unused includes
+	NoAdd    bool
+	NoRemove bool
+	// LocalSystem is used for finding unused system includes. If nil, only unused project includes are removed.
+	LocalSystem *LocalSystem
+	// Write writes the changed files. If false, only the diffs are returned.
+	Write bool
+}
+
+// FileFix is the change to a single file that FixIncludes makes, or would make
+type FileFix struct {
+	File    string    `json:"file"`
+	Added   []Include `json:"added,omitempty"`   // the added #include lines, with their line numbers in the new file
+	Removed []Include `json:"removed,omitempty"` // the removed #include lines, with their line numbers in the old file
+	// Diff is a unified diff of the change, with paths relative to the root path
+	Diff string `json:"diff"`
+}
+
+// fixLine is a line of a file that is being fixed
+type fixLine struct {
+	text    string
+	line    int  // the line number in the original file, or 0 for added lines
+	removed bool // the line is removed
+}
+
+// FixIncludes adds the missing direct includes that MissingDirectIncludes finds and removes the unused
+// includes that UnusedIncludes finds. Added includes are placed in the group of includes of the same
+// category, in sorted order if the group is sorted, and include lines within #if blocks are never added
+// to or removed. By default, only the diffs are returned, and opts.Write is needed for writing the
+// files, which is only possible when the sources are read from the OS file system. After writing,
+// Refresh can be used for reading the changed files.
+func (src *Sources) FixIncludes(opts FixOptions) ([]FileFix, error) {
+	if opts.Write && !src.osFS {
+		return nil, errors.New("only files on the OS file system can be written")
+	}
+	toAdd := make(map[string][]string)
+	if !opts.NoAdd {
+		for _, m := range src.MissingDirectIncludes() {
+			if m.Confidence >= opts.MinConfidence {
+				toAdd[m.File] = appendUnique(toAdd[m.File], m.Header)
+			}
+		}
+	}
+	toRemove := make(map[string][]UnusedInclude)
+	if !opts.NoRemove {
+		for _, unused := range src.UnusedIncludes(opts.LocalSystem) {
+			if unused.Confidence >= opts.MinConfidence {
+				toRemove[unused.File] = append(toRemove[unused.File], unused)
+			}
+		}
+	}
+	var fixes []FileFix
+	for _, filename := range src.AllFilenames() {
+		if len(toAdd[filename]) == 0 && len(toRemove[filename]) == 0 {
+			continue
+		}
+		f, ok := src.files[filename]
+		if !ok {
+			continue
+		}
+		fix, data := fixFile(filename, f.data, toAdd[filename], toRemove[filename])
+		if len(fix.Added) == 0 && len(fix.Removed) == 0 {
+			continue
+		}
+		fix.Diff = unifiedDiff(src.fsPath(filename), fix.script)
+		if opts.Write {
+			if err := writeFile(filename, data); err != nil {
+				return fixes, err
+			}
+		}
+		fixes = append(fixes, fix.FileFix)
+	}
+	sort.SliceStable(fixes, func(i, j int) bool {
+		return fixes[i].File < fixes[j].File
+	})
+	return fixes, nil
+}
+
+// fileFix is a FileFix together with the edit script that the diff is made from
+type fileFix struct {
+	FileFix
+	script []diffLine
+}
+
+// includeGroup is a run of #include lines without blank lines between them, outside of #if blocks
+type includeGroup struct {
+	start, end int // the indices of the first line and after the last line, in the fixLine slice
+	category   IncludeCategory
+	names      []string
+}
+
+// writeFile replaces the contents of a file, but keeps its permissions
+func writeFile(filename string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+	return os.WriteFile(filename, data, mode)
+}
+
+// fixFile adds and removes #include lines in the given file contents, and returns the fix and the new contents
+func fixFile(filename string, data []byte, add []string, remove []UnusedInclude) (fileFix, []byte) {
+	fix := fileFix{FileFix: FileFix{File: filename}}
+	text := string(data)
+	trailingNewline := strings.HasSuffix(text, "\n")
+	text = strings.TrimSuffix(text, "\n")
+	newline := "\n"
+	if strings.Contains(text, "\r\n") {
+		newline = "\r\n"
+	}
+	var lines []fixLine
+	for i, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		lines = append(lines, fixLine{text: line, line: i + 1})
+	}
+	conditional := conditionalLines(data)
+
+	// Remove the unused includes, and a blank line if the include was alone in its group
+	for _, unused := range remove {
+		i := unused.Line - 1
+		if i < 0 || i >= len(lines) || conditional[unused.Line] {
+			continue
+		}
+		if include, ok := parseIncludeLine(lines[i].text); !ok || include.Name != unused.Name {
+			continue
+		}
+		lines[i].removed = true
+		fix.Removed = append(fix.Removed, unused.Include)
+	}
+	for i := range lines {
+		if !lines[i].removed || lines[i].line == 0 {
+			continue
+		}
+		if _, ok := parseIncludeLine(lines[i].text); !ok {
+			continue
+		}
+		prev, next := -1, -1
+		for j := i - 1; j >= 0; j-- {
+			if !lines[j].removed {
+				prev = j
+				break
+			}
+		}
+		for j := i + 1; j < len(lines); j++ {
+			if !lines[j].removed {
+				next = j
+				break
+			}
+		}
+		if next != -1 && strings.TrimSpace(lines[next].text) == "" && (prev == -1 || strings.TrimSpace(lines[prev].text) == "") {
+			lines[next].removed = true
+		}
+	}
+
+	// Add the missing includes
+	for _, header := range add {
+		include := Include{File: filename, Name: header, System: true}
+		lines = insertInclude(lines, include, conditional)
+	}
+
+	var sb strings.Builder
+	newLine := 0
+	for _, line := range lines {
+		switch {
+		case line.removed:
+			fix.script = append(fix.script, diffLine{kind: '-', text: line.text})
+			continue
+		case line.line == 0:
+			fix.script = append(fix.script, diffLine{kind: '+', text: line.text})
+			if include, ok := parseIncludeLine(line.text); ok {
+				include.File = filename
+				include.Line = newLine + 1
+				fix.Added = append(fix.Added, include)
+			}
+		default:
+			fix.script = append(fix.script, diffLine{kind: ' ', text: line.text})
+		}
+		if newLine > 0 {
+			sb.WriteString(newline)
+		}
+		sb.WriteString(line.text)
+		newLine++
+	}
+	if trailingNewline {
+		sb.WriteString(newline)
+	}
+	return fix, []byte(sb.String())
+}
+
+// includeGroups returns the groups of #include lines that are not within #if blocks
+func includeGroups(filename string, lines []fixLine, conditional map[int]bool) []includeGroup {
+	var groups []includeGroup
+	var current *includeGroup
+	for i, line := range lines {
+		if line.removed {
+			continue
+		}
+		include, ok := parseIncludeLine(line.text)
+		if !ok || (line.line != 0 && conditional[line.line]) {
+			if current != nil {
+				groups = append(groups, *current)
+				current = nil
+			}
+			continue
+		}
+		include.File = filename
+		if current == nil {
+			current = &includeGroup{start: i, category: CategoryOf(include)}
+		}
+		current.end = i + 1
+		current.names = append(current.names, include.Name)
+		if category := CategoryOf(include); category > current.category {
+			// A mixed group counts as the latest category in it
+			current.category = category
+		}
+	}
+	if current != nil {
+		groups = append(groups, *current)
+	}
+	return groups
+}
+
+// insertInclude adds an #include line to the group of includes with the same category, at the sorted
+// position if the group is sorted and else last. If there is no such group, a new group is added before
+// the first group with a later category, or after the last group.
+func insertInclude(lines []fixLine, include Include, conditional map[int]bool) []fixLine {
+	category := CategoryOf(include)
+	groups := includeGroups(include.File, lines, conditional)
+	newLine := fixLine{text: include.String()}
+	insertAt := func(i int, newLines ...fixLine) []fixLine {
+		return append(lines[:i], append(newLines, lines[i:]...)...)
+	}
+	// The group of the same category with the most includes
+	best := -1
+	for i, group := range groups {
+		if group.category == category && (best == -1 || len(group.names) > len(groups[best].names)) {
+			best = i
+		}
+	}
+	if best != -1 {
+		group := groups[best]
+		if !sort.StringsAreSorted(group.names) {
+			return insertAt(group.end, newLine)
+		}
+		// Skip the removed lines when counting the sorted position
+		position := 0
+		for i := group.start; i < group.end; i++ {
+			if lines[i].removed {
+				continue
+			}
+			other, ok := parseIncludeLine(lines[i].text)
+			if ok && other.Name > include.Name {
+				return insertAt(i, newLine)
+			}
+			position = i + 1
+		}
+		return insertAt(position, newLine)
+	}
+	blank := fixLine{text: ""}
+	for _, group := range groups {
+		if group.category > category {
+			return insertAt(group.start, newLine, blank)
+		}
+	}
+	if len(groups) > 0 {
+		return insertAt(groups[len(groups)-1].end, blank, newLine)
+	}
+	// There are no includes, so add the include after the include guard or #pragma once,
+	// and after any comments at the top of the file
+	i := 0
+	for i < len(lines) {
+		trimmed := strings.TrimSpace(lines[i].text)
+		name, rest, isDirective := directive(lines[i].text)
+		switch {
+		case lines[i].removed, trimmed == "", strings.HasPrefix(trimmed, "//"):
+		case strings.HasPrefix(trimmed, "/*"):
+			for i < len(lines) && !strings.Contains(lines[i].text, "*/") {
+				i++
+			}
+		case isDirective && name == "pragma" && strings.HasPrefix(rest, "once"):
+		case isDirective && name == "ifndef" && i+1 < len(lines):
+			if nextName, nextRest, ok := directive(lines[i+1].text); ok && nextName == "define" && firstIdentifier(nextRest) == firstIdentifier(rest) {
+				i++
+			} else {
+				return insertAt(i, newLine, blank)
+			}
+		default:
+			return insertAt(i, newLine, blank)
+		}
+		i++
+	}
+	return insertAt(len(lines), newLine)
+}
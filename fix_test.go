@@ -0,0 +1,92 @@
+package autocpp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+const fixTestMain = `// main.cpp
+#include "main.h"
+
+#include <cstdint>
+#include <string>
+
+#include "unused.h"
+#include "widget.h"
+#ifdef DEBUG
+#include "debug.h"
+#endif
+
+int main() {
+	std::vector<std::string> names;
+	uint32_t x = helper();
+	printf("%d\n", x);
+	return 0;
+}
+`
+
+func TestFixIncludes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.cpp": {Data: []byte(fixTestMain)},
+		"main.h":   {Data: []byte("#pragma once\n")},
+		"unused.h": {Data: []byte("#pragma once\nint unused_function(void);\n")},
+		"widget.h": {Data: []byte("#pragma once\n#include <vector>\nint helper(void);\n")},
+		"debug.h":  {Data: []byte("#pragma once\nvoid trace(const char *s);\n")},
+		"alloc.c":  {Data: []byte("/* Allocation\n * helpers */\n\nvoid *allocate(void) { return malloc(1); }\n")},
+	}
+	src, err := NewSourcesWithOptions("project", SourcesOptions{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.FixIncludes(FixOptions{Write: true}); err == nil {
+		t.Errorf("expected an error when writing files that are not on the OS file system")
+	}
+	fixes, err := src.FixIncludes(FixOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixes) != 2 {
+		t.Fatalf("expected fixes for two files, got %+v", fixes)
+	}
+	expected := "--- a/alloc.c\n+++ b/alloc.c\n@@ -1,4 +1,6 @@\n" +
+		" /* Allocation\n  * helpers */\n \n+#include <stdlib.h>\n+\n void *allocate(void) { return malloc(1); }\n"
+	if fixes[0].File != "project/alloc.c" || fixes[0].Diff != expected {
+		t.Errorf("unexpected fix for %s:\n%s", fixes[0].File, fixes[0].Diff)
+	}
+	expected = "--- a/main.cpp\n+++ b/main.cpp\n@@ -2,9 +2,10 @@\n" +
+		" #include \"main.h\"\n \n #include <cstdint>\n+#include <cstdio>\n #include <string>\n+#include <vector>\n \n" +
+		"-#include \"unused.h\"\n #include \"widget.h\"\n #ifdef DEBUG\n #include \"debug.h\"\n"
+	if fixes[1].File != "project/main.cpp" || fixes[1].Diff != expected {
+		t.Errorf("unexpected fix for %s:\n%s", fixes[1].File, fixes[1].Diff)
+	}
+	if len(fixes[1].Removed) != 1 || fixes[1].Removed[0].Line != 7 || len(fixes[1].Added) != 2 || fixes[1].Added[1].Line != 7 {
+		t.Errorf("unexpected added and removed includes: %+v", fixes[1])
+	}
+}
+
+func TestFixIncludesWrite(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, "main.c", "unused.h")
+	if err := os.WriteFile(filepath.Join(root, "unused.h"), []byte("int unused_function(void);\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "main.c"), []byte("#include <stdio.h>\n\n#include \"unused.h\"\n\nint main(void) { printf(\"hi\"); }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	src, err := NewSources(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.FixIncludes(FixOptions{MinConfidence: ConfidenceHigh, Write: true}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "main.c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "#include <stdio.h>\n\nint main(void) { printf(\"hi\"); }\n" {
+		t.Errorf("unexpected contents after fixing:\n%s", data)
+	}
+}
@@ -0,0 +1,106 @@
+package autocpp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BuildFlags are the flags that are needed for building the sources on a LocalSystem
+type BuildFlags struct {
+	// IncludeDirectories are the directories that must be given with -I, relative to the root path
+	// for directories within the project
+	IncludeDirectories []string `json:"include_directories,omitempty"`
+	// Libraries are the pkg-config names of the libraries that are needed, like "sdl2"
+	Libraries []string `json:"libraries,omitempty"`
+	// CFlags and Libs are the output of "pkg-config --cflags" and "pkg-config --libs" for the libraries
+	// that pkg-config knows about
+	CFlags []string `json:"cflags,omitempty"`
+	Libs   []string `json:"libs,omitempty"`
+	// LinkFlags are flags for system libraries, like -pthread
+	LinkFlags []string `json:"link_flags,omitempty"`
+}
+
+// CompileFlags returns the flags for compiling, like "-Iinclude" and the cflags from pkg-config
+func (flags BuildFlags) CompileFlags() []string {
+	var compileFlags []string
+	for _, dir := range flags.IncludeDirectories {
+		compileFlags = append(compileFlags, "-I"+dir)
+	}
+	return appendUnique(compileFlags, flags.CFlags...)
+}
+
+// LinkerFlags returns the flags for linking, the libs from pkg-config followed by the link flags
+func (flags BuildFlags) LinkerFlags() []string {
+	return appendUnique(append([]string{}, flags.Libs...), flags.LinkFlags...)
+}
+
+// BuildFlags finds the include paths of the sources and returns the flags that are needed for building them.
+// The include directories within the project are inferred from where the project headers are, and come first.
+// pkg-config is used for libraries that are known, but pkg-config is optional.
+func (src *Sources) BuildFlags(locsys *LocalSystem) BuildFlags {
+	var flags BuildFlags
+	src.FindIncludePaths(locsys)
+	flags.IncludeDirectories = appendUnique(flags.IncludeDirectories, src.InferIncludeDirectories(locsys).Directories...)
+	searchPath := src.searchPath(locsys)
+	for _, include := range src.ShortIncludes() {
+		path, ok := src.foundMap[include]
+		if !ok || !strings.HasSuffix(filepath.ToSlash(path), "/"+include) {
+			continue
+		}
+		dir := filepath.Clean(path[:len(path)-len(include)-1])
+		if hasS(searchPath, dir) || isCompilerDirectory(dir) {
+			continue
+		}
+		if rel, err := filepath.Rel(src.rootPath, dir); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			if rel == "." {
+				// The compiler finds "..." includes next to the including file, and the build runs in the root path
+				continue
+			}
+			dir = filepath.ToSlash(rel)
+		}
+		flags.IncludeDirectories = appendUnique(flags.IncludeDirectories, dir)
+	}
+	flags.Libraries = src.Libraries()
+	pc := locsys.PackageConfig()
+	for _, library := range flags.Libraries {
+		cflags, err := pc.Run("--cflags", library)
+		if err != nil {
+			// Not installed, or pkg-config is missing
+			continue
+		}
+		libs, err := pc.Run("--libs", library)
+		if err != nil {
+			continue
+		}
+		flags.CFlags = appendUnique(flags.CFlags, strings.Fields(cflags)...)
+		flags.Libs = appendUnique(flags.Libs, strings.Fields(libs)...)
+	}
+	flags.LinkFlags = src.LinkFlags()
+	return flags
+}
+
+// isCompilerDirectory checks if the given directory is one that the compiler always searches,
+// like /usr/include/c++/12 for the C++ standard library of GCC
+func isCompilerDirectory(dir string) bool {
+	return strings.Contains(filepath.ToSlash(dir)+"/", "/c++/")
+}
+
+// ProgramName returns the name of the executable that the sources are built into,
+// which is the name of the root directory
+func (src *Sources) ProgramName() string {
+	rootPath := src.rootPath
+	if absRootPath, err := filepath.Abs(rootPath); err == nil {
+		rootPath = absRootPath
+	}
+	name := filepath.Base(rootPath)
+	if name == string(os.PathSeparator) || name == "." || name == "" {
+		return "main"
+	}
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '$' || r == ':' {
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,183 @@
+package autocpp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Generators are the names of the build systems that build files can be generated for
+var Generators = []string{"make", "cmake", "ninja"}
+
+// Generate writes a build file for the given build system, which is "make", "cmake" or "ninja",
+// that builds the sources into a single executable with the given flags
+func (src *Sources) Generate(w io.Writer, buildSystem string, flags BuildFlags) error {
+	switch buildSystem {
+	case "make":
+		return src.WriteMakefile(w, flags)
+	case "cmake":
+		return src.WriteCMakeLists(w, flags)
+	case "ninja":
+		return src.WriteNinja(w, flags)
+	}
+	return fmt.Errorf("unknown build system %q, expected one of: %s", buildSystem, strings.Join(Generators, ", "))
+}
+
+// sourcePaths returns the slash-separated paths of the C and C++ files, relative to the root path
+func (src *Sources) sourcePaths() []string {
+	var paths []string
+	for _, name := range src.CPPFilenames() {
+		paths = append(paths, src.fsPath(name))
+	}
+	for _, name := range src.CFilenames() {
+		paths = append(paths, src.fsPath(name))
+	}
+	return paths
+}
+
+// objectPath returns the object file for the given source file. The extension of the source file is kept,
+// like "foo.c.o", so that foo.c and foo.cpp do not share an object file.
+func objectPath(sourcePath string) string {
+	return sourcePath + ".o"
+}
+
+// WriteMakefile writes a Makefile for GNU Make
+func (src *Sources) WriteMakefile(w io.Writer, flags BuildFlags) error {
+	bw := bufio.NewWriter(w)
+	program := src.ProgramName()
+	sourcePaths := src.sourcePaths()
+	linker := "$(CXX)"
+	if len(src.CPPFilenames()) == 0 {
+		linker = "$(CC)"
+	}
+	fmt.Fprintf(bw, "# Generated by autocpp\n\n")
+	fmt.Fprintf(bw, "PROGRAM := %s\n", program)
+	fmt.Fprintf(bw, "SRCS := %s\n", strings.Join(sourcePaths, " "))
+	fmt.Fprintf(bw, "OBJS := %s\n\n", strings.Join(mapS(sourcePaths, objectPath), " "))
+	fmt.Fprintf(bw, "CFLAGS ?= -O2 -Wall\n")
+	fmt.Fprintf(bw, "CXXFLAGS ?= -O2 -Wall\n")
+	if compileFlags := flags.CompileFlags(); len(compileFlags) > 0 {
+		fmt.Fprintf(bw, "CPPFLAGS += %s\n", strings.Join(compileFlags, " "))
+	}
+	if linkerFlags := flags.LinkerFlags(); len(linkerFlags) > 0 {
+		fmt.Fprintf(bw, "LDLIBS += %s\n", strings.Join(linkerFlags, " "))
+	}
+	fmt.Fprintf(bw, "\n$(PROGRAM): $(OBJS)\n\t%s $(LDFLAGS) -o $@ $^ $(LDLIBS)\n", linker)
+	var exts []string
+	for _, sourcePath := range sourcePaths {
+		if ext := path.Ext(sourcePath); !hasS(exts, ext) {
+			exts = append(exts, ext)
+		}
+	}
+	for _, ext := range exts {
+		if kindOf(ext) == cFile {
+			fmt.Fprintf(bw, "\n%%%s.o: %%%s\n\t$(CC) $(CPPFLAGS) $(CFLAGS) -c -o $@ $<\n", ext, ext)
+		} else {
+			fmt.Fprintf(bw, "\n%%%s.o: %%%s\n\t$(CXX) $(CPPFLAGS) $(CXXFLAGS) -c -o $@ $<\n", ext, ext)
+		}
+	}
+	fmt.Fprintf(bw, "\n.PHONY: clean\nclean:\n\trm -f $(PROGRAM) $(OBJS)\n")
+	return bw.Flush()
+}
+
+// WriteCMakeLists writes a CMakeLists.txt file. Libraries are found with the PkgConfig module of CMake.
+func (src *Sources) WriteCMakeLists(w io.Writer, flags BuildFlags) error {
+	bw := bufio.NewWriter(w)
+	program := src.ProgramName()
+	var languages []string
+	for _, lang := range src.Languages() {
+		if lang == LanguageCXX {
+			languages = append(languages, "CXX")
+		} else {
+			languages = append(languages, "C")
+		}
+	}
+	fmt.Fprintf(bw, "# Generated by autocpp\n\n")
+	fmt.Fprintf(bw, "cmake_minimum_required(VERSION 3.12)\n")
+	fmt.Fprintf(bw, "project(%s LANGUAGES %s)\n\n", program, strings.Join(languages, " "))
+	fmt.Fprintf(bw, "add_executable(%s\n", program)
+	for _, sourcePath := range src.sourcePaths() {
+		fmt.Fprintf(bw, "  %s\n", cmakeQuote(sourcePath))
+	}
+	fmt.Fprintf(bw, ")\n")
+	if len(flags.IncludeDirectories) > 0 {
+		fmt.Fprintf(bw, "\ntarget_include_directories(%s PRIVATE\n", program)
+		for _, dir := range flags.IncludeDirectories {
+			fmt.Fprintf(bw, "  %s\n", cmakeQuote(dir))
+		}
+		fmt.Fprintf(bw, ")\n")
+	}
+	if len(flags.Libraries) > 0 {
+		fmt.Fprintf(bw, "\nfind_package(PkgConfig REQUIRED)\n")
+		fmt.Fprintf(bw, "pkg_check_modules(DEPS REQUIRED IMPORTED_TARGET %s)\n", strings.Join(flags.Libraries, " "))
+		fmt.Fprintf(bw, "target_link_libraries(%s PRIVATE PkgConfig::DEPS)\n", program)
+	}
+	var systemLibraries []string
+	for _, flag := range flags.LinkFlags {
+		switch {
+		case flag == "-pthread":
+			fmt.Fprintf(bw, "\nset(THREADS_PREFER_PTHREAD_FLAG ON)\nfind_package(Threads REQUIRED)\n")
+			fmt.Fprintf(bw, "target_link_libraries(%s PRIVATE Threads::Threads)\n", program)
+		case strings.HasPrefix(flag, "-l"):
+			systemLibraries = append(systemLibraries, strings.TrimPrefix(flag, "-l"))
+		}
+	}
+	if len(systemLibraries) > 0 {
+		fmt.Fprintf(bw, "\ntarget_link_libraries(%s PRIVATE %s)\n", program, strings.Join(systemLibraries, " "))
+	}
+	return bw.Flush()
+}
+
+// cmakeQuote quotes the given argument for CMake, if needed
+func cmakeQuote(s string) string {
+	if !strings.ContainsAny(s, " \t\";$()#") {
+		return s
+	}
+	return "\"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "$", "\\$").Replace(s) + "\""
+}
+
+// WriteNinja writes a build.ninja file
+func (src *Sources) WriteNinja(w io.Writer, flags BuildFlags) error {
+	bw := bufio.NewWriter(w)
+	program := src.ProgramName()
+	linker := "$cxx"
+	if len(src.CPPFilenames()) == 0 {
+		linker = "$cc"
+	}
+	fmt.Fprintf(bw, "# Generated by autocpp\n\n")
+	fmt.Fprintf(bw, "cc = cc\ncxx = c++\n")
+	fmt.Fprintf(bw, "cflags = -O2 -Wall\ncxxflags = -O2 -Wall\n")
+	fmt.Fprintf(bw, "cppflags = %s\n", strings.Join(flags.CompileFlags(), " "))
+	fmt.Fprintf(bw, "ldlibs = %s\n\n", strings.Join(flags.LinkerFlags(), " "))
+	fmt.Fprintf(bw, "rule cc\n  command = $cc $cppflags $cflags -MMD -MF $out.d -c $in -o $out\n  depfile = $out.d\n  deps = gcc\n\n")
+	fmt.Fprintf(bw, "rule cxx\n  command = $cxx $cppflags $cxxflags -MMD -MF $out.d -c $in -o $out\n  depfile = $out.d\n  deps = gcc\n\n")
+	fmt.Fprintf(bw, "rule link\n  command = %s $in -o $out $ldlibs\n\n", linker)
+	var objects []string
+	for _, sourcePath := range src.sourcePaths() {
+		rule := "cxx"
+		if kindOf(sourcePath) == cFile {
+			rule = "cc"
+		}
+		object := ninjaEscape(objectPath(sourcePath))
+		objects = append(objects, object)
+		fmt.Fprintf(bw, "build %s: %s %s\n", object, rule, ninjaEscape(sourcePath))
+	}
+	fmt.Fprintf(bw, "\nbuild %s: link %s\n\ndefault %s\n", ninjaEscape(program), strings.Join(objects, " "), ninjaEscape(program))
+	return bw.Flush()
+}
+
+// ninjaEscape escapes a path for a build statement in a ninja file
+func ninjaEscape(s string) string {
+	return strings.NewReplacer("$", "$$", " ", "$ ", ":", "$:").Replace(s)
+}
+
+// mapS returns the result of calling f on each element of xs
+func mapS(xs []string, f func(string) string) []string {
+	result := make([]string, len(xs))
+	for i, x := range xs {
+		result[i] = f(x)
+	}
+	return result
+}
@@ -0,0 +1,96 @@
+package autocpp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGenerate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.cpp":      {Data: []byte("#include <SDL2/SDL.h>\n#include <thread>\n#include \"game.h\"\n")},
+		"src/game.cc":   {Data: []byte("#include \"game.h\"\n")},
+		"util.c":        {Data: []byte("#include <math.h>\n")},
+		"util.cpp":      {Data: []byte("\n")},
+		"legacy.C":      {Data: []byte("\n")},
+		"inc/game.h":    {Data: []byte("#pragma once\n")},
+		"space dir/x.c": {Data: []byte("\n")},
+	}
+	src, err := NewSourcesWithOptions("/tmp/my game", SourcesOptions{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := src.ProgramName(); name != "my_game" {
+		t.Errorf("expected the program name my_game, got %q", name)
+	}
+	flags := BuildFlags{
+		IncludeDirectories: []string{"inc"},
+		Libraries:          []string{"sdl2"},
+		CFlags:             []string{"-I/usr/include/SDL2", "-D_REENTRANT"},
+		Libs:               []string{"-lSDL2"},
+		LinkFlags:          src.LinkFlags(),
+	}
+	expected := map[string][]string{
+		"make": {
+			"SRCS := main.cpp src/game.cc util.cpp legacy.C space dir/x.c util.c\n",
+			"OBJS := main.cpp.o src/game.cc.o util.cpp.o legacy.C.o space dir/x.c.o util.c.o\n",
+			"CPPFLAGS += -Iinc -I/usr/include/SDL2 -D_REENTRANT\n",
+			"LDLIBS += -lSDL2 -pthread -lm\n",
+			"%.cc.o: %.cc\n\t$(CXX) $(CPPFLAGS) $(CXXFLAGS) -c -o $@ $<\n",
+			"%.c.o: %.c\n\t$(CC) $(CPPFLAGS) $(CFLAGS) -c -o $@ $<\n",
+			"%.C.o: %.C\n\t$(CC) $(CPPFLAGS) $(CFLAGS) -c -o $@ $<\n",
+		},
+		"cmake": {
+			"project(my_game LANGUAGES CXX C)\n",
+			"  \"space dir/x.c\"\n",
+			"target_include_directories(my_game PRIVATE\n  inc\n)\n",
+			"pkg_check_modules(DEPS REQUIRED IMPORTED_TARGET sdl2)\n",
+			"target_link_libraries(my_game PRIVATE Threads::Threads)\n",
+			"target_link_libraries(my_game PRIVATE m)\n",
+		},
+		"ninja": {
+			"cppflags = -Iinc -I/usr/include/SDL2 -D_REENTRANT\n",
+			"ldlibs = -lSDL2 -pthread -lm\n",
+			"build src/game.cc.o: cxx src/game.cc\n",
+			"build space$ dir/x.c.o: cc space$ dir/x.c\n",
+			"build util.c.o: cc util.c\n",
+			"build util.cpp.o: cxx util.cpp\n",
+			"build legacy.C.o: cc legacy.C\n",
+			"build my_game: link main.cpp.o src/game.cc.o util.cpp.o legacy.C.o space$ dir/x.c.o util.c.o\n",
+		},
+	}
+	for _, buildSystem := range Generators {
+		var buf bytes.Buffer
+		if err := src.Generate(&buf, buildSystem, flags); err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range expected[buildSystem] {
+			if !strings.Contains(buf.String(), s) {
+				t.Errorf("expected the %s file to contain %q, got:\n%s", buildSystem, s, buf.String())
+			}
+		}
+	}
+	if err := src.Generate(&bytes.Buffer{}, "scons", flags); err == nil {
+		t.Error("expected an error for an unknown build system")
+	}
+}
+
+func TestBuildFlags(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.cpp":       {Data: []byte("#include <SDL.h>\n#include <vector>\n#include \"widget.h\"\n")},
+		"inc/widget.h":   {Data: []byte("#pragma once\n")},
+		"other/unused.h": {Data: []byte("\n")},
+	}
+	src, err := NewSourcesWithOptions("project", SourcesOptions{FS: fsys, LocalIncludeDirectories: []string{"inc"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	flags := src.BuildFlags(newTestSystem(t))
+	if strings.Join(flags.IncludeDirectories, " ") != "inc /usr/include/SDL2" {
+		t.Errorf("unexpected include directories: %v", flags.IncludeDirectories)
+	}
+	if strings.Join(flags.Libraries, " ") != "sdl" {
+		t.Errorf("unexpected libraries: %v", flags.Libraries)
+	}
+}
@@ -0,0 +1,127 @@
+package autocpp
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ResolveProjectInclude finds the project file that the given #include line refers to, if any.
+// Like for compilers, "..." includes are first looked up relative to the including file. Then the
+// local include directories of the project are searched, and finally any project file that ends
+// with the short include name is used.
+func (src *Sources) ResolveProjectInclude(include Include) (string, bool) {
+	if !include.System {
+		candidate := filepath.Join(filepath.Dir(include.File), filepath.FromSlash(include.Name))
+		if _, ok := src.files[candidate]; ok {
+			return candidate, true
+		}
+	}
+	for _, includeDirectory := range src.LocalIncludeDirectories() {
+		candidate := src.name(path.Join(includeDirectory, include.Name))
+		if _, ok := src.files[candidate]; ok {
+			return candidate, true
+		}
+	}
+	if found, ok := src.foundMap[include.Name]; ok {
+		if _, ok := src.files[found]; ok {
+			return found, true
+		}
+	}
+	suffix := string(filepath.Separator) + filepath.FromSlash(include.Name)
+	var candidates []string
+	for _, header := range src.absFilenamesHeader {
+		if strings.HasSuffix(header, suffix) || header == filepath.FromSlash(include.Name) {
+			candidates = append(candidates, header)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+	return shortest(candidates), true
+}
+
+// IncludeGraph returns a map from each source file to the sorted project files that it includes
+func (src *Sources) IncludeGraph() map[string][]string {
+	graph := make(map[string][]string)
+	for _, path := range src.AllFilenames() {
+		var edges []string
+		for _, include := range src.FileIncludes(path) {
+			if target, ok := src.ResolveProjectInclude(include); ok && !hasS(edges, target) {
+				edges = append(edges, target)
+			}
+		}
+		sort.Strings(edges)
+		graph[path] = edges
+	}
+	return graph
+}
+
+// Cycles returns the include cycles among the project files. Each cycle is a sorted list of the
+// files that include each other, directly or indirectly, and the cycles are sorted by their first file.
+func (src *Sources) Cycles() [][]string {
+	return findCycles(src.IncludeGraph())
+}
+
+// findCycles finds the strongly connected components of the graph with more than one node,
+// or with a node that has an edge to itself, using Tarjan's algorithm
+func findCycles(graph map[string][]string) [][]string {
+	var (
+		index   = make(map[string]int)
+		lowlink = make(map[string]int)
+		onStack = make(map[string]bool)
+		stack   []string
+		cycles  [][]string
+		counter int
+	)
+	var strongConnect func(v string)
+	strongConnect = func(v string) {
+		index[v] = counter
+		lowlink[v] = counter
+		counter++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range graph[v] {
+			if _, visited := index[w]; !visited {
+				strongConnect(w)
+				if lowlink[w] < lowlink[v] {
+					lowlink[v] = lowlink[w]
+				}
+			} else if onStack[w] && index[w] < lowlink[v] {
+				lowlink[v] = index[w]
+			}
+		}
+		if lowlink[v] != index[v] {
+			return
+		}
+		var component []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			component = append(component, w)
+			if w == v {
+				break
+			}
+		}
+		if len(component) > 1 || hasS(graph[v], v) {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+	nodes := make([]string, 0, len(graph))
+	for node := range graph {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	for _, node := range nodes {
+		if _, visited := index[node]; !visited {
+			strongConnect(node)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+	return cycles
+}
@@ -0,0 +1,442 @@
+package autocpp
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// GuardProblem is a kind of problem with the include guard of a header
+type GuardProblem int
+
+const (
+	// GuardMissing is a header without an include guard and without #pragma once
+	GuardMissing GuardProblem = iota
+	// GuardMismatch is an #ifndef that is followed by a #define of another name
+	GuardMismatch
+	// GuardCollision is an include guard that is also used by another header
+	GuardCollision
+	// GuardMissingEndif is an include guard without the #endif at the end
+	GuardMissingEndif
+	// GuardName is an include guard that does not follow the naming scheme
+	GuardName
+)
+
+var guardProblemNames = []string{"missing", "mismatch", "collision", "missing-endif", "name"}
+
+// String returns the name of the problem, like "mismatch"
+func (problem GuardProblem) String() string {
+	if problem < 0 || int(problem) >= len(guardProblemNames) {
+		return fmt.Sprintf("GuardProblem(%d)", int(problem))
+	}
+	return guardProblemNames[problem]
+}
+
+// MarshalText makes the problem appear by name in JSON and YAML
+func (problem GuardProblem) MarshalText() ([]byte, error) {
+	return []byte(problem.String()), nil
+}
+
+// GuardScheme says how the include guard of a header is named after its path, relative to the project.
+// With the zero value, include/foo/bar-baz.h gets the guard INCLUDE_FOO_BAR_BAZ_H.
+type GuardScheme struct {
+	// Prefix is added to the start of the name, like "MYPROJECT_"
+	Prefix string
+	// Suffix is added to the end of the name, like "_"
+	Suffix string
+	// StripDirectories are leading directories that are not part of the name, like "include" or "src"
+	StripDirectories []string
+	// BaseName is true if only the file name is used, and not the directories
+	BaseName bool
+}
+
+// GuardName returns the include guard for the header with the given slash-separated path
+func (scheme GuardScheme) GuardName(relativePath string) string {
+	p := path.Clean(relativePath)
+	for _, dir := range scheme.StripDirectories {
+		if prefix := strings.Trim(dir, "/") + "/"; strings.HasPrefix(p, prefix) {
+			p = strings.TrimPrefix(p, prefix)
+			break
+		}
+	}
+	if scheme.BaseName {
+		p = path.Base(p)
+	}
+	var sb strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			sb.WriteByte(c - 'a' + 'A')
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			sb.WriteByte(c)
+		case sb.Len() > 0 && !strings.HasSuffix(sb.String(), "_"):
+			sb.WriteByte('_')
+		}
+	}
+	name := scheme.Prefix + strings.TrimSuffix(sb.String(), "_") + scheme.Suffix
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "HEADER_" + name
+	}
+	return name
+}
+
+// GuardOptions can be used for configuring CheckHeaderGuards and FixHeaderGuards
+type GuardOptions struct {
+	// Scheme is used for naming new guards, and guards that are renamed because they collide
+	Scheme GuardScheme
+	// CheckNames is true if guards that do not follow the scheme are also reported
+	CheckNames bool
+	// Write is true if FixHeaderGuards should write the fixed files. This only works for files on the OS file system.
+	Write bool
+}
+
+// GuardIssue is a problem with the include guard of a header
+type GuardIssue struct {
+	File    string       `json:"file"`
+	Line    int          `json:"line"`
+	Problem GuardProblem `json:"problem"`
+	// Guard is the name of the include guard, if any
+	Guard   string `json:"guard,omitempty"`
+	Message string `json:"message"`
+	// Others are the other headers that use the same guard, for collisions
+	Others []string `json:"others,omitempty"`
+}
+
+// String returns the issue as "file:line: message [problem]"
+func (issue GuardIssue) String() string {
+	return fmt.Sprintf("%s:%d: %s [%s]", issue.File, issue.Line, issue.Message, issue.Problem)
+}
+
+// GuardFix is the fixed include guard of a header
+type GuardFix struct {
+	File  string `json:"file"`
+	Guard string `json:"guard"`
+	// Diff is a unified diff of the changes
+	Diff string `json:"diff"`
+}
+
+// headerGuard is the include guard of a header, as found by parseHeaderGuard
+type headerGuard struct {
+	name       string // the name in the #ifndef, or "" if there is no guard
+	defined    string // the name in the #define
+	pragmaOnce bool
+	ifndefLine int // the line numbers of the #ifndef, #define and #endif, or 0
+	defineLine int
+	endifLine  int
+	firstCode  int // the index of the first line that is not blank or a comment
+}
+
+// parseHeaderGuard finds the include guard of a header. An #ifndef NAME or #if !defined(NAME) that is
+// followed by a #define only counts as a guard if its #endif ends the header, or if there is no #endif
+// and the names match.
+func parseHeaderGuard(data []byte) headerGuard {
+	var guard headerGuard
+	lines := logicalLines(stripComments(data))
+	guard.firstCode = len(lines)
+	candidate := -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if guard.firstCode == len(lines) {
+			guard.firstCode = i
+		}
+		name, rest, ok := directive(line)
+		if ok && name == "pragma" && firstIdentifier(rest) == "once" {
+			guard.pragmaOnce = true
+			continue
+		}
+		if candidate < 0 {
+			candidate = i
+		}
+	}
+	if candidate < 0 {
+		return guard
+	}
+	name, rest, ok := directive(lines[candidate])
+	switch {
+	case ok && name == "ifndef":
+		guard.name = firstIdentifier(rest)
+	case ok && name == "if" && strings.HasPrefix(rest, "!"):
+		rest = strings.TrimSpace(rest[1:])
+		if !strings.HasPrefix(rest, "defined") {
+			return guard
+		}
+		guard.name = firstIdentifier(strings.TrimSpace(strings.TrimLeft(strings.TrimPrefix(rest, "defined"), " \t(")))
+	}
+	if guard.name == "" {
+		return guard
+	}
+	guard.ifndefLine = candidate + 1
+	for i := candidate + 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" {
+			continue
+		}
+		if name, rest, ok := directive(lines[i]); ok && name == "define" {
+			guard.defined = firstIdentifier(rest)
+			guard.defineLine = i + 1
+		}
+		break
+	}
+	if guard.defined == "" {
+		return headerGuard{pragmaOnce: guard.pragmaOnce, firstCode: guard.firstCode}
+	}
+	depth := 0
+	for i := candidate; i < len(lines); i++ {
+		switch name, _, _ := directive(lines[i]); name {
+		case "if", "ifdef", "ifndef":
+			depth++
+		case "endif":
+			depth--
+		}
+		if depth == 0 {
+			guard.endifLine = i + 1
+			break
+		}
+	}
+	// The guard covers the header if nothing comes after the #endif
+	covers := guard.endifLine > 0 && strings.TrimSpace(strings.Join(lines[guard.endifLine:], "")) == ""
+	if (guard.endifLine > 0 && !covers) || (guard.name != guard.defined && !covers) {
+		// This is a regular #ifndef block, not an include guard
+		return headerGuard{pragmaOnce: guard.pragmaOnce, firstCode: guard.firstCode}
+	}
+	return guard
+}
+
+// CheckHeaderGuards checks that every header has an include guard or #pragma once, that the #ifndef
+// and #define of each guard use the same name, that the guard ends with an #endif and that no two
+// headers use the same guard. The issues are ordered by file.
+func (src *Sources) CheckHeaderGuards(opts GuardOptions) []GuardIssue {
+	issues, _ := src.checkHeaderGuards(opts)
+	return issues
+}
+
+// checkHeaderGuards returns the issues, and the parsed guards of the headers
+func (src *Sources) checkHeaderGuards(opts GuardOptions) ([]GuardIssue, map[string]headerGuard) {
+	var issues []GuardIssue
+	guards := make(map[string]headerGuard)
+	users := make(map[string][]string) // guard name to headers
+	for _, filename := range src.absFilenamesHeader {
+		f, ok := src.files[filename]
+		if !ok {
+			continue
+		}
+		guard := parseHeaderGuard(f.data)
+		guards[filename] = guard
+		switch {
+		case guard.name == "":
+			if !guard.pragmaOnce {
+				issues = append(issues, GuardIssue{File: filename, Line: 1, Problem: GuardMissing, Message: "no include guard or #pragma once"})
+			}
+			continue
+		case guard.name != guard.defined:
+			issues = append(issues, GuardIssue{File: filename, Line: guard.defineLine, Problem: GuardMismatch, Guard: guard.name,
+				Message: fmt.Sprintf("#ifndef %s is followed by #define %s", guard.name, guard.defined)})
+		case guard.endifLine == 0:
+			issues = append(issues, GuardIssue{File: filename, Line: guard.ifndefLine, Problem: GuardMissingEndif, Guard: guard.name,
+				Message: fmt.Sprintf("the include guard %s has no #endif", guard.name)})
+		}
+		if expected := opts.Scheme.GuardName(src.fsPath(filename)); opts.CheckNames && guard.name != expected {
+			issues = append(issues, GuardIssue{File: filename, Line: guard.ifndefLine, Problem: GuardName, Guard: guard.name,
+				Message: fmt.Sprintf("the include guard %s should be named %s", guard.name, expected)})
+		}
+		users[guard.name] = append(users[guard.name], filename)
+	}
+	for name, filenames := range users {
+		if len(filenames) < 2 {
+			continue
+		}
+		for _, filename := range filenames {
+			var others []string
+			for _, other := range filenames {
+				if other != filename {
+					others = append(others, other)
+				}
+			}
+			issues = append(issues, GuardIssue{File: filename, Line: guards[filename].ifndefLine, Problem: GuardCollision, Guard: name,
+				Message: fmt.Sprintf("the include guard %s is also used by %s", name, strings.Join(others, ", ")), Others: others})
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Problem < b.Problem
+	})
+	return issues, guards
+}
+
+// FixHeaderGuards fixes the issues that CheckHeaderGuards finds. Missing guards are added around the code
+// of the header, after any leading comments, and a missing #endif is added at the end. A #define that does
+// not match its #ifndef is renamed. Colliding guards and, with opts.CheckNames, guards that do not follow
+// the scheme are renamed to the name from the scheme, except for the first header of a collision that
+// already follows it. The fixes are ordered by file.
+func (src *Sources) FixHeaderGuards(opts GuardOptions) ([]GuardFix, error) {
+	if opts.Write && !src.osFS {
+		return nil, errors.New("only files on the OS file system can be written")
+	}
+	issues, guards := src.checkHeaderGuards(opts)
+	problems := make(map[string][]GuardProblem)
+	var filenames []string
+	for _, issue := range issues {
+		if len(problems[issue.File]) == 0 {
+			filenames = append(filenames, issue.File)
+		}
+		problems[issue.File] = append(problems[issue.File], issue.Problem)
+	}
+	var fixes []GuardFix
+	for _, filename := range filenames {
+		guard := guards[filename]
+		name := guard.name
+		expected := opts.Scheme.GuardName(src.fsPath(filename))
+		for _, problem := range problems[filename] {
+			if problem == GuardMissing || problem == GuardName || (problem == GuardCollision && !src.keepsGuard(filename, guard.name, guards, opts.Scheme)) {
+				name = expected
+			}
+		}
+		data, script := fixHeaderGuard(src.files[filename].data, guard, name)
+		diff := unifiedDiff(src.fsPath(filename), script)
+		if diff == "" {
+			continue
+		}
+		if opts.Write {
+			if err := writeFile(filename, data); err != nil {
+				return fixes, err
+			}
+		}
+		fixes = append(fixes, GuardFix{File: filename, Guard: name, Diff: diff})
+	}
+	return fixes, nil
+}
+
+// keepsGuard checks if the given header keeps its colliding guard, which is the case for the first
+// header that uses it and whose name follows the scheme, or else for the first header that uses it
+func (src *Sources) keepsGuard(filename, name string, guards map[string]headerGuard, scheme GuardScheme) bool {
+	var first, firstExpected string
+	for _, header := range src.absFilenamesHeader {
+		if guard, ok := guards[header]; !ok || guard.name != name {
+			continue
+		}
+		if first == "" {
+			first = header
+		}
+		if firstExpected == "" && scheme.GuardName(src.fsPath(header)) == name {
+			firstExpected = header
+		}
+	}
+	if firstExpected != "" {
+		return filename == firstExpected
+	}
+	return filename == first
+}
+
+// fixHeaderGuard returns the fixed contents of a header whose guard should be named name, and the edit script
+func fixHeaderGuard(data []byte, guard headerGuard, name string) ([]byte, []diffLine) {
+	text := string(data)
+	trailingNewline := strings.HasSuffix(text, "\n") || text == ""
+	text = strings.TrimSuffix(text, "\n")
+	newline := "\n"
+	if strings.Contains(text, "\r\n") {
+		newline = "\r\n"
+	}
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if len(data) == 0 {
+		lines = nil
+	}
+	var script []diffLine
+	replace := func(i int, line string) {
+		if line != lines[i] {
+			script = append(script, diffLine{'-', lines[i]}, diffLine{'+', line})
+		} else {
+			script = append(script, diffLine{' ', line})
+		}
+		lines[i] = line
+	}
+	endif := "#endif // " + name
+	addGuard := guard.name == "" && !guard.pragmaOnce
+	var added []string
+	for i := range lines {
+		switch {
+		case addGuard && i == guard.firstCode:
+			for _, line := range []string{"#ifndef " + name, "#define " + name, ""} {
+				script = append(script, diffLine{'+', line})
+				added = append(added, line)
+			}
+			script = append(script, diffLine{' ', lines[i]})
+			added = append(added, lines[i])
+			addGuard = false
+			continue
+		case guard.name != "" && (i+1 == guard.ifndefLine || i+1 == guard.endifLine):
+			replace(i, replaceIdentifier(lines[i], guard.name, name))
+		case guard.name != "" && i+1 == guard.defineLine:
+			replace(i, replaceIdentifier(lines[i], guard.defined, name))
+		default:
+			script = append(script, diffLine{' ', lines[i]})
+		}
+		added = append(added, lines[i])
+	}
+	lines = added
+	switch {
+	case guard.name == "" && !guard.pragmaOnce:
+		if addGuard {
+			// The header has no code, so the guard goes at the end
+			for _, line := range []string{"#ifndef " + name, "#define " + name} {
+				script = append(script, diffLine{'+', line})
+				lines = append(lines, line)
+			}
+		}
+		for _, line := range []string{"", endif} {
+			script = append(script, diffLine{'+', line})
+			lines = append(lines, line)
+		}
+		trailingNewline = true
+	case guard.name != "" && guard.endifLine == 0:
+		for _, line := range []string{"", endif} {
+			script = append(script, diffLine{'+', line})
+			lines = append(lines, line)
+		}
+		trailingNewline = true
+	}
+	// Group the removed lines before the added lines of each change
+	for start := 0; start < len(script); start++ {
+		end := start
+		for end < len(script) && script[end].kind != ' ' {
+			end++
+		}
+		sort.SliceStable(script[start:end], func(i, j int) bool {
+			return script[start+i].kind == '-' && script[start+j].kind == '+'
+		})
+		start = end
+	}
+	fixed := strings.Join(lines, newline)
+	if trailingNewline {
+		fixed += newline
+	}
+	return []byte(fixed), script
+}
+
+// replaceIdentifier replaces the identifier old with new in the given line
+func replaceIdentifier(line, old, new string) string {
+	if old == new || old == "" {
+		return line
+	}
+	var sb strings.Builder
+	for i := 0; i < len(line); {
+		if strings.HasPrefix(line[i:], old) && (i == 0 || !isIdentifierByte(line[i-1])) &&
+			(i+len(old) == len(line) || !isIdentifierByte(line[i+len(old)])) {
+			sb.WriteString(new)
+			i += len(old)
+			continue
+		}
+		sb.WriteByte(line[i])
+		i++
+	}
+	return sb.String()
+}
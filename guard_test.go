@@ -0,0 +1,112 @@
+package autocpp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGuardName(t *testing.T) {
+	for _, test := range []struct {
+		scheme   GuardScheme
+		path     string
+		expected string
+	}{
+		{GuardScheme{}, "include/foo/bar-baz.h", "INCLUDE_FOO_BAR_BAZ_H"},
+		{GuardScheme{Prefix: "MYLIB_", Suffix: "_", StripDirectories: []string{"include"}}, "include/foo/bar.hpp", "MYLIB_FOO_BAR_HPP_"},
+		{GuardScheme{BaseName: true}, "src/widget.h", "WIDGET_H"},
+		{GuardScheme{}, "3d.h", "HEADER_3D_H"},
+	} {
+		if name := test.scheme.GuardName(test.path); name != test.expected {
+			t.Errorf("expected %s for %s, got %s", test.expected, test.path, name)
+		}
+	}
+}
+
+func TestCheckHeaderGuards(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.cpp":      {Data: []byte("#include \"a.h\"\n")},
+		"a.h":           {Data: []byte("// A\n#ifndef A_H\n#define A_H\nint a;\n#endif // A_H\n")},
+		"once.h":        {Data: []byte("#pragma once\nint once;\n")},
+		"missing.h":     {Data: []byte("/* Missing */\n\nint missing;\n")},
+		"mismatch.h":    {Data: []byte("#ifndef MISMATCH_H\n#define MISMATCH_HH\nint mismatch;\n#endif\n")},
+		"endif.h":       {Data: []byte("#ifndef ENDIF_H\n#define ENDIF_H\nint endif;\n")},
+		"copy/a.h":      {Data: []byte("#if !defined(A_H)\n#define A_H\nint copy;\n#endif\n")},
+		"conditional.h": {Data: []byte("#ifndef NDEBUG\n#define DEBUG 1\n#endif\nint conditional;\n")},
+	}
+	src, err := NewSourcesWithOptions("project", SourcesOptions{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []string
+	for _, issue := range src.CheckHeaderGuards(GuardOptions{}) {
+		results = append(results, issue.String())
+	}
+	expected := []string{
+		"project/a.h:2: the include guard A_H is also used by project/copy/a.h [collision]",
+		"project/conditional.h:1: no include guard or #pragma once [missing]",
+		"project/copy/a.h:1: the include guard A_H is also used by project/a.h [collision]",
+		"project/endif.h:1: the include guard ENDIF_H has no #endif [missing-endif]",
+		"project/mismatch.h:2: #ifndef MISMATCH_H is followed by #define MISMATCH_HH [mismatch]",
+		"project/missing.h:1: no include guard or #pragma once [missing]",
+	}
+	if strings.Join(results, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected issues:\n%s", strings.Join(results, "\n"))
+	}
+	issues := src.CheckHeaderGuards(GuardOptions{CheckNames: true})
+	if len(issues) != 7 || issues[3].Problem != GuardName || issues[3].File != "project/copy/a.h" {
+		t.Errorf("expected the names of the guards to be checked, got %+v", issues)
+	}
+
+	if _, err := src.FixHeaderGuards(GuardOptions{Write: true}); err == nil {
+		t.Errorf("expected an error when writing files that are not on the OS file system")
+	}
+	fixes, err := src.FixHeaderGuards(GuardOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs := make(map[string]string)
+	for _, fix := range fixes {
+		diffs[fix.File] = fix.Diff
+	}
+	if len(fixes) != 5 || diffs["project/a.h"] != "" {
+		t.Errorf("unexpected fixes: %+v", fixes)
+	}
+	for filename, expected := range map[string]string{
+		"project/copy/a.h": "--- a/copy/a.h\n+++ b/copy/a.h\n@@ -1,4 +1,4 @@\n-#if !defined(A_H)\n-#define A_H\n+#if !defined(COPY_A_H)\n+#define COPY_A_H\n int copy;\n #endif\n",
+		"project/endif.h":  "--- a/endif.h\n+++ b/endif.h\n@@ -1,3 +1,5 @@\n #ifndef ENDIF_H\n #define ENDIF_H\n int endif;\n+\n+#endif // ENDIF_H\n",
+		"project/mismatch.h": "--- a/mismatch.h\n+++ b/mismatch.h\n@@ -1,4 +1,4 @@\n #ifndef MISMATCH_H\n-#define MISMATCH_HH\n+#define MISMATCH_H\n" +
+			" int mismatch;\n #endif\n",
+		"project/missing.h": "--- a/missing.h\n+++ b/missing.h\n@@ -1,3 +1,8 @@\n /* Missing */\n \n+#ifndef MISSING_H\n+#define MISSING_H\n+\n" +
+			" int missing;\n+\n+#endif // MISSING_H\n",
+	} {
+		if diffs[filename] != expected {
+			t.Errorf("unexpected fix for %s:\n%s", filename, diffs[filename])
+		}
+	}
+}
+
+func TestFixHeaderGuardsWrite(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, "include/empty.h")
+	src, err := NewSources(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixes, err := src.FixHeaderGuards(GuardOptions{Scheme: GuardScheme{Prefix: "PROJECT_", StripDirectories: []string{"include"}}, Write: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixes) != 1 || fixes[0].Guard != "PROJECT_EMPTY_H" {
+		t.Errorf("unexpected fixes: %+v", fixes)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "include", "empty.h"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "\n#ifndef PROJECT_EMPTY_H\n#define PROJECT_EMPTY_H\n\n#endif // PROJECT_EMPTY_H\n" {
+		t.Errorf("unexpected contents after fixing:\n%q", data)
+	}
+}
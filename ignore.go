@@ -0,0 +1,169 @@
+package autocpp
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// gitignorePattern is a single pattern from a .gitignore file
+type gitignorePattern struct {
+	base     string // the directory of the .gitignore file, relative to the root, "." for the root
+	re       *regexp.Regexp
+	negate   bool // the pattern started with "!"
+	dirOnly  bool // the pattern ended with "/"
+	anchored bool // the pattern contained a "/" that was not at the end, so it is relative to base
+}
+
+// gitignore holds the patterns of all .gitignore files that have been read so far while walking a tree
+type gitignore struct {
+	patterns []gitignorePattern
+}
+
+// parseGitignore parses the contents of a .gitignore file in the given directory
+func parseGitignore(base string, data []byte) []gitignorePattern {
+	var patterns []gitignorePattern
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		// Trailing spaces are ignored, unless they are escaped with a backslash
+		if !strings.HasSuffix(line, "\\ ") {
+			line = strings.TrimRight(line, " \t")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var p gitignorePattern
+		p.base = base
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			p.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		re, err := globToRegexp(line)
+		if err != nil {
+			continue
+		}
+		p.re = re
+		patterns = append(patterns, p)
+	}
+	return patterns
+}
+
+// globToRegexp converts a glob pattern with support for "**" to a regular expression that matches a
+// whole slash-separated path. "*" and "?" do not match "/", while "**/", "/**" and "/**/" match any
+// number of directories.
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "/**") && i+3 == len(pattern):
+			sb.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, "\\", "\\\\") + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// read reads the .gitignore file in the given directory, if there is one
+func (gi *gitignore) read(fsys fs.FS, dir string) {
+	if data, err := fs.ReadFile(fsys, path.Join(dir, ".gitignore")); err == nil {
+		gi.patterns = append(gi.patterns, parseGitignore(dir, data)...)
+	}
+}
+
+// ignored checks if the given slash-separated path, relative to the root, is ignored.
+// Like for git, the last matching pattern decides, and patterns in deeper .gitignore files come later.
+func (gi *gitignore) ignored(p string, isDir bool) bool {
+	ignored := false
+	for _, pattern := range gi.patterns {
+		if pattern.dirOnly && !isDir {
+			continue
+		}
+		rel := p
+		if pattern.base != "." {
+			if !strings.HasPrefix(p, pattern.base+"/") {
+				continue
+			}
+			rel = strings.TrimPrefix(p, pattern.base+"/")
+		}
+		var matched bool
+		if pattern.anchored {
+			matched = pattern.re.MatchString(rel)
+		} else {
+			matched = pattern.re.MatchString(path.Base(rel))
+		}
+		if matched {
+			ignored = !pattern.negate
+		}
+	}
+	return ignored
+}
+
+// globs is a list of compiled glob patterns, like "build", "third_party/*" or "**/generated/*.h"
+type globs []*regexp.Regexp
+
+// compileGlobs compiles the given glob patterns. Patterns that are not valid are skipped.
+func compileGlobs(patterns []string) globs {
+	var gl globs
+	for _, pattern := range patterns {
+		if re, err := globToRegexp(strings.TrimSuffix(filepath.ToSlash(pattern), "/")); err == nil {
+			gl = append(gl, re)
+		}
+	}
+	return gl
+}
+
+// match checks if the given slash-separated path, or its base name, matches any of the glob patterns
+func (gl globs) match(p string) bool {
+	base := path.Base(p)
+	for _, re := range gl {
+		if re.MatchString(p) || re.MatchString(base) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package autocpp
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGitignore(t *testing.T) {
+	var gi gitignore
+	gi.patterns = append(gi.patterns, parseGitignore(".", []byte("# build output\nbuild/\n*.o\n/generated.h\n!keep.o\ndocs/**/*.h\n**/cmake-build-*\n\\#hash.h\n"))...)
+	gi.patterns = append(gi.patterns, parseGitignore("src", []byte("local_only.h\n!/generated.h\n"))...)
+	tests := []struct {
+		p       string
+		isDir   bool
+		ignored bool
+	}{
+		{"build", true, true},
+		{"build", false, false}, // a file named build is not a directory
+		{"src/build", true, true},
+		{"main.o", false, true},
+		{"src/main.o", false, true},
+		{"keep.o", false, false},
+		{"generated.h", false, true},
+		{"src/generated.h", false, false}, // anchored to the root .gitignore
+		{"docs/a/b/api.h", false, true},
+		{"docs/api.h", false, true},
+		{"other/cmake-build-debug", true, true},
+		{"#hash.h", false, true},
+		{"src/local_only.h", false, true},
+		{"local_only.h", false, false}, // only ignored below src
+		{"main.cpp", false, false},
+	}
+	for _, test := range tests {
+		if got := gi.ignored(test.p, test.isDir); got != test.ignored {
+			t.Errorf("ignored(%q, %v): expected %v, got %v", test.p, test.isDir, test.ignored, got)
+		}
+	}
+}
+
+func TestGlobs(t *testing.T) {
+	gl := compileGlobs([]string{"third_party/*", "**/gen/*.h", "*.pb.h"})
+	for p, expected := range map[string]bool{
+		"third_party/zlib":     true,
+		"third_party/zlib/z.h": false,
+		"a/b/gen/x.h":          true,
+		"gen/x.h":              true,
+		"src/msg.pb.h":         true,
+		"src/msg.h":            false,
+	} {
+		if got := gl.match(p); got != expected {
+			t.Errorf("match(%q): expected %v, got %v", p, expected, got)
+		}
+	}
+}
+
+func TestSourcesGitignore(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore":                   {Data: []byte("build/\ncmake-build-*/\n*.generated.h\n")},
+		".git/info/exclude":            {Data: []byte("scratch.cpp\n")},
+		".git/hooks/pre-commit.c":      {Data: []byte("#include <git.h>\n")},
+		"main.cpp":                     {Data: []byte("#include <vector>\n")},
+		"scratch.cpp":                  {Data: []byte("#include <scratch.h>\n")},
+		"build/moc_main.cpp":           {Data: []byte("#include <QObject>\n")},
+		"cmake-build-debug/x.cpp":      {Data: []byte("#include <cmake.h>\n")},
+		"src/api.generated.h":          {Data: []byte("#include <protobuf.h>\n")},
+		"src/.gitignore":               {Data: []byte("*.h\n!keep.h\n")},
+		"src/keep.h":                   {Data: []byte("#include <string>\n")},
+		"src/drop.h":                   {Data: []byte("#include <dropped.h>\n")},
+		"third_party/zlib/zlib.h":      {Data: []byte("#include <zconf.h>\n")},
+		"tests/test_main.cpp":          {Data: []byte("#include <gtest/gtest.h>\n")},
+		"tests/fixtures/fixture.c":     {Data: []byte("#include <fixture.h>\n")},
+		"tools/codegen/codegen.cpp":    {Data: []byte("#include <map>\n")},
+		"tools/codegen/codegen_main.h": {Data: []byte("#include <set>\n")},
+	}
+	src, err := NewSourcesWithOptions("project", SourcesOptions{
+		FS:      fsys,
+		Exclude: []string{"third_party", "tests/fixtures"},
+		Include: []string{"*.cpp", "src/*.h", "tools/**/*.h"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"gtest/gtest.h", "map", "set", "string", "vector"}
+	if shortIncludes := src.ShortIncludes(); !reflect.DeepEqual(shortIncludes, expected) {
+		t.Errorf("expected %v, got %v", expected, shortIncludes)
+	}
+	src, err = NewSourcesWithOptions("project", SourcesOptions{FS: fsys, IgnoreGitignore: true, Exclude: []string{"third_party", "tests", "tools"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"QObject", "cmake.h", "dropped.h", "protobuf.h", "scratch.h", "string", "vector"}
+	if shortIncludes := src.ShortIncludes(); !reflect.DeepEqual(shortIncludes, expected) {
+		t.Errorf("expected %v, got %v", expected, shortIncludes)
+	}
+}
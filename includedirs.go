@@ -0,0 +1,103 @@
+package autocpp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Language is either C or C++
+type Language int
+
+const (
+	LanguageC Language = iota
+	LanguageCXX
+)
+
+// String returns "c" or "c++"
+func (lang Language) String() string {
+	if lang == LanguageC {
+		return "c"
+	}
+	return "c++"
+}
+
+// MarshalText makes the language appear as "c" or "c++" in JSON and YAML
+func (lang Language) MarshalText() ([]byte, error) {
+	return []byte(lang.String()), nil
+}
+
+// EnvironmentIncludeDirectories contains the include directories from the environment variables
+// that GCC and Clang use: CPATH applies to both C and C++, C_INCLUDE_PATH only to C and
+// CPLUS_INCLUDE_PATH only to C++.
+type EnvironmentIncludeDirectories struct {
+	Common []string
+	C      []string
+	CXX    []string
+}
+
+// ReadEnvironmentIncludeDirectories reads CPATH, C_INCLUDE_PATH and CPLUS_INCLUDE_PATH
+func ReadEnvironmentIncludeDirectories() EnvironmentIncludeDirectories {
+	return environmentIncludeDirectories(os.Getenv)
+}
+
+func environmentIncludeDirectories(getenv func(string) string) EnvironmentIncludeDirectories {
+	return EnvironmentIncludeDirectories{
+		Common: splitIncludePath(getenv("CPATH")),
+		C:      splitIncludePath(getenv("C_INCLUDE_PATH")),
+		CXX:    splitIncludePath(getenv("CPLUS_INCLUDE_PATH")),
+	}
+}
+
+// splitIncludePath splits a list of directories, like the value of $CPATH.
+// GCC treats empty elements as the current directory, but they are skipped here,
+// since the current directory is already one of the local include directories.
+func splitIncludePath(s string) []string {
+	var xs []string
+	for _, dir := range filepath.SplitList(s) {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			xs = append(xs, filepath.Clean(dir))
+		}
+	}
+	return xs
+}
+
+// ParseIncludeFlags picks out the include directories from compiler flags like
+// "-Iinclude", "-I include", "-iquote src" and "-isystem /opt/sdk/include".
+// Directories given with -I and -iquote are returned as local include directories,
+// directories given with -isystem and -idirafter are returned as system include directories.
+func ParseIncludeFlags(flags []string) (localIncludeDirectories, systemIncludeDirectories []string) {
+	for i := 0; i < len(flags); i++ {
+		for _, prefix := range []string{"-I", "-iquote", "-isystem", "-idirafter"} {
+			if !strings.HasPrefix(flags[i], prefix) {
+				continue
+			}
+			dir := strings.TrimPrefix(flags[i], prefix)
+			if dir == "" && i+1 < len(flags) {
+				i++
+				dir = flags[i]
+			}
+			if dir == "" {
+				break
+			}
+			switch prefix {
+			case "-I", "-iquote":
+				localIncludeDirectories = append(localIncludeDirectories, filepath.Clean(dir))
+			default:
+				systemIncludeDirectories = append(systemIncludeDirectories, filepath.Clean(dir))
+			}
+			break
+		}
+	}
+	return localIncludeDirectories, systemIncludeDirectories
+}
+
+// appendUnique appends the elements of ys to xs that are not already in xs
+func appendUnique(xs []string, ys ...string) []string {
+	for _, y := range ys {
+		if !hasS(xs, y) {
+			xs = append(xs, y)
+		}
+	}
+	return xs
+}
@@ -0,0 +1,51 @@
+package autocpp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnvironmentIncludeDirectories(t *testing.T) {
+	environment := map[string]string{
+		"CPATH":              "/opt/sdk/include::/usr/local/sdk/",
+		"C_INCLUDE_PATH":     "/opt/c/include",
+		"CPLUS_INCLUDE_PATH": "/opt/cxx/include:/opt/cxx/include2",
+	}
+	dirs := environmentIncludeDirectories(func(name string) string {
+		return environment[name]
+	})
+	if !reflect.DeepEqual(dirs.Common, []string{"/opt/sdk/include", "/usr/local/sdk"}) {
+		t.Errorf("unexpected CPATH directories: %v", dirs.Common)
+	}
+	if !reflect.DeepEqual(dirs.C, []string{"/opt/c/include"}) {
+		t.Errorf("unexpected C_INCLUDE_PATH directories: %v", dirs.C)
+	}
+	if !reflect.DeepEqual(dirs.CXX, []string{"/opt/cxx/include", "/opt/cxx/include2"}) {
+		t.Errorf("unexpected CPLUS_INCLUDE_PATH directories: %v", dirs.CXX)
+	}
+}
+
+func TestParseIncludeFlags(t *testing.T) {
+	local, system := ParseIncludeFlags([]string{"-O2", "-Iinclude", "-I", "../common", "-iquote", "src", "-isystem", "/opt/sdk/include", "-idirafter/opt/late", "-include", "pch.hpp"})
+	if !reflect.DeepEqual(local, []string{"include", "../common", "src"}) {
+		t.Errorf("unexpected local include directories: %v", local)
+	}
+	if !reflect.DeepEqual(system, []string{"/opt/sdk/include", "/opt/late"}) {
+		t.Errorf("unexpected system include directories: %v", system)
+	}
+}
+
+func TestSearchPath(t *testing.T) {
+	locsys := &LocalSystem{
+		systemIncludeDirectories: []string{"/usr/include"},
+		extraIncludeDirectories:  []string{"/opt/sdk/include"},
+		cIncludeDirectories:      []string{"/opt/c/include"},
+		cxxIncludeDirectories:    []string{"/opt/cxx/include"},
+	}
+	if searchPath := locsys.SearchPath(LanguageC); !reflect.DeepEqual(searchPath, []string{"/opt/sdk/include", "/opt/c/include", "/usr/include"}) {
+		t.Errorf("unexpected C search path: %v", searchPath)
+	}
+	if searchPath := locsys.SearchPath(LanguageCXX); !reflect.DeepEqual(searchPath, []string{"/opt/sdk/include", "/opt/cxx/include", "/usr/include"}) {
+		t.Errorf("unexpected C++ search path: %v", searchPath)
+	}
+}
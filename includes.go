@@ -0,0 +1,123 @@
+package autocpp
+
+import (
+	"crypto/sha256"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// Include is a single #include line in a source file
+type Include struct {
+	File   string `json:"file"`   // the file that contains the #include line
+	Line   int    `json:"line"`   // the line number, starting at 1
+	Name   string `json:"name"`   // the short include name, like "SDL2/SDL.h"
+	System bool   `json:"system"` // true for #include <...> and false for #include "..."
+}
+
+// String returns the include as it would be written in a source file
+func (include Include) String() string {
+	if include.System {
+		return "#include <" + include.Name + ">"
+	}
+	return "#include \"" + include.Name + "\""
+}
+
+// sourceFile is a source file that has been read, with the information that is needed for
+// checking if it has changed
+type sourceFile struct {
+	name     string // the filename, including the root path
+	data     []byte
+	size     int64
+	modTime  time.Time
+	hash     [sha256.Size]byte
+	includes []Include
+}
+
+// readFile reads and parses a single source file
+func (src *Sources) readFile(name string) (*sourceFile, error) {
+	p := src.fsPath(name)
+	data, err := fs.ReadFile(src.fsys, p)
+	if err != nil {
+		return nil, err
+	}
+	f := &sourceFile{name: name, data: data, size: int64(len(data)), hash: sha256.Sum256(data)}
+	if info, err := fs.Stat(src.fsys, p); err == nil {
+		f.modTime = info.ModTime()
+	}
+	f.includes = parseIncludes(name, data)
+	return f, nil
+}
+
+// parseIncludes returns the #include lines in the given file contents.
+// Both "#include <...>" and "# include \"...\"" are recognized, and trailing comments are ignored.
+func parseIncludes(filename string, data []byte) []Include {
+	var includes []Include
+	for i, line := range strings.Split(string(data), "\n") {
+		if include, ok := parseIncludeLine(line); ok {
+			include.File = filename
+			include.Line = i + 1
+			includes = append(includes, include)
+		}
+	}
+	return includes
+}
+
+// parseIncludeLine parses a single line, and returns false if it is not an #include line with a "..." or <...> name
+func parseIncludeLine(line string) (Include, bool) {
+	trimmedLine := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmedLine, "#") {
+		return Include{}, false
+	}
+	trimmedLine = strings.TrimSpace(trimmedLine[1:])
+	if !strings.HasPrefix(trimmedLine, "include") {
+		return Include{}, false
+	}
+	rest := strings.TrimSpace(strings.TrimPrefix(trimmedLine, "include"))
+	if rest == "" {
+		return Include{}, false
+	}
+	var include Include
+	switch rest[0] {
+	case '<':
+		end := strings.IndexByte(rest[1:], '>')
+		if end < 0 {
+			return Include{}, false
+		}
+		include.Name = rest[1 : end+1]
+		include.System = true
+	case '"':
+		end := strings.IndexByte(rest[1:], '"')
+		if end < 0 {
+			return Include{}, false
+		}
+		include.Name = rest[1 : end+1]
+	default:
+		// A macro, like #include FT_FREETYPE_H, names a header that is only known after preprocessing,
+		// and "include" may also be the start of another directive, like #include_next
+		return Include{}, false
+	}
+	if include.Name == "" {
+		return Include{}, false
+	}
+	return include, true
+}
+
+// Includes returns all #include lines in all source files, ordered by file and then by line
+func (src *Sources) Includes() []Include {
+	var includes []Include
+	for _, path := range src.AllFilenames() {
+		if f, ok := src.files[path]; ok {
+			includes = append(includes, f.includes...)
+		}
+	}
+	return includes
+}
+
+// FileIncludes returns the #include lines in the given source file
+func (src *Sources) FileIncludes(filename string) []Include {
+	if f, ok := src.files[filename]; ok {
+		return f.includes
+	}
+	return nil
+}
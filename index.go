@@ -0,0 +1,59 @@
+package autocpp
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// buildIndex creates the lookup tables for locsys.includeFiles. It must be called whenever
+// locsys.includeFiles changes.
+func (locsys *LocalSystem) buildIndex() {
+	locsys.includeSet = make(map[string]struct{}, len(locsys.includeFiles))
+	locsys.baseIndex = make(map[string][]string)
+	for _, includeFile := range locsys.includeFiles {
+		locsys.includeSet[includeFile] = struct{}{}
+		base := filepath.Base(includeFile)
+		locsys.baseIndex[base] = append(locsys.baseIndex[base], includeFile)
+	}
+}
+
+// HasIncludeFile checks if the given path is one of the include files that were found
+func (locsys *LocalSystem) HasIncludeFile(path string) bool {
+	_, ok := locsys.includeSet[path]
+	return ok
+}
+
+// Candidates returns the sorted include files that end with the given include name,
+// like "/usr/include/SDL2/SDL.h" for "SDL2/SDL.h".
+func (locsys *LocalSystem) Candidates(include string) []string {
+	include = filepath.FromSlash(include)
+	suffix := string(filepath.Separator) + include
+	var candidates []string
+	// Only the include files with the same base name need to be checked
+	for _, includeFile := range locsys.baseIndex[path.Base(filepath.ToSlash(include))] {
+		if strings.HasSuffix(includeFile, suffix) {
+			candidates = append(candidates, includeFile)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// FindIncludeFile returns the include file for the given short include name, like "/usr/include/SDL2/SDL.h"
+// for "SDL2/SDL.h". The C++ search path is searched first, then the C search path, and then the include
+// files that end with the include name.
+func (locsys *LocalSystem) FindIncludeFile(include string) (string, bool) {
+	for _, lang := range []Language{LanguageCXX, LanguageC} {
+		for _, includeDirectory := range locsys.SearchPath(lang) {
+			if path := filepath.Join(includeDirectory, include); locsys.HasIncludeFile(path) {
+				return path, true
+			}
+		}
+	}
+	if candidates := locsys.Candidates(include); len(candidates) > 0 {
+		return locsys.rank(include, candidates).Path, true
+	}
+	return "", false
+}
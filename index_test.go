@@ -0,0 +1,93 @@
+package autocpp
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// newBenchmarkSystem returns a LocalSystem with the given number of generated include files
+func newBenchmarkSystem(n int) *LocalSystem {
+	locsys := &LocalSystem{systemIncludeDirectories: []string{"/usr/include"}}
+	for i := 0; i < n; i++ {
+		locsys.includeFiles = append(locsys.includeFiles, fmt.Sprintf("/usr/include/lib%03d/sub%d/header%05d.h", i%500, i%7, i))
+	}
+	locsys.includeFiles = append(locsys.includeFiles, "/usr/include/c++/12/vector", "/usr/include/c++/12/debug/vector", "/usr/include/SDL2/SDL.h")
+	locsys.buildIndex()
+	return locsys
+}
+
+// benchmarkIncludes returns include names for the benchmark system, some of which are not found
+func benchmarkIncludes(n int) []string {
+	includes := []string{"vector", "SDL2/SDL.h", "missing.h"}
+	for i := 0; i < 200; i++ {
+		includes = append(includes, fmt.Sprintf("sub%d/header%05d.h", (i*97)%7, (i*97)%n))
+	}
+	return includes
+}
+
+// findLinear is how include files were found before there was an index, for comparison
+func findLinear(locsys *LocalSystem, include string) (bool, []string) {
+	found := hasS(locsys.includeFiles, "/usr/include/"+include)
+	var candidates []string
+	for _, includeFile := range locsys.includeFiles {
+		if strings.HasSuffix(includeFile, "/"+include) {
+			candidates = append(candidates, includeFile)
+		}
+	}
+	sort.Strings(candidates)
+	return found, candidates
+}
+
+func findIndexed(locsys *LocalSystem, include string) (bool, []string) {
+	return locsys.HasIncludeFile("/usr/include/" + include), locsys.Candidates(include)
+}
+
+func TestIndexMatchesLinear(t *testing.T) {
+	locsys := newBenchmarkSystem(5000)
+	for _, include := range benchmarkIncludes(5000) {
+		linearFound, linearCandidates := findLinear(locsys, include)
+		indexedFound, indexedCandidates := findIndexed(locsys, include)
+		if linearFound != indexedFound || !reflect.DeepEqual(linearCandidates, indexedCandidates) {
+			t.Errorf("%s: the linear search found %v %v, the index found %v %v", include, linearFound, linearCandidates, indexedFound, indexedCandidates)
+		}
+	}
+	if candidates := locsys.Candidates("vector"); !reflect.DeepEqual(candidates, []string{"/usr/include/c++/12/debug/vector", "/usr/include/c++/12/vector"}) {
+		t.Errorf("unexpected candidates for vector: %v", candidates)
+	}
+	if candidates := locsys.Candidates("12/vector"); len(candidates) != 1 {
+		t.Errorf("expected one candidate for 12/vector, got %v", candidates)
+	}
+}
+
+func BenchmarkFindLinear50k(b *testing.B) {
+	locsys := newBenchmarkSystem(50000)
+	includes := benchmarkIncludes(50000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, include := range includes {
+			findLinear(locsys, include)
+		}
+	}
+}
+
+func BenchmarkFindIndexed50k(b *testing.B) {
+	locsys := newBenchmarkSystem(50000)
+	includes := benchmarkIncludes(50000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, include := range includes {
+			findIndexed(locsys, include)
+		}
+	}
+}
+
+func BenchmarkBuildIndex50k(b *testing.B) {
+	locsys := newBenchmarkSystem(50000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		locsys.buildIndex()
+	}
+}
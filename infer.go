@@ -0,0 +1,226 @@
+package autocpp
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IncludeDirectoryInference is the result of InferIncludeDirectories
+type IncludeDirectoryInference struct {
+	// Directories are the directories, relative to the root path, that must be given with -I,
+	// in the order they must be given
+	Directories []string `json:"directories"`
+	// Includes are the #include lines that refer to project headers, and how they are resolved
+	Includes []InferredInclude `json:"includes"`
+	// Ambiguities are about includes that match several project headers
+	Ambiguities []string `json:"ambiguities,omitempty"`
+	// Conflicts are about includes that can not all be resolved as intended with any order of the directories
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+// InferredInclude is an #include line that refers to a project header
+type InferredInclude struct {
+	Include
+	// Header is the project header that the include refers to, and Directory is the directory it
+	// is found in, both relative to the root path. Directory is empty if the header is found next
+	// to the including file.
+	Header    string `json:"header"`
+	Directory string `json:"directory,omitempty"`
+	// Candidates are all project headers that the include could refer to, if there are several
+	Candidates []string `json:"candidates,omitempty"`
+}
+
+// headerCandidate is a project header that an include could refer to
+type headerCandidate struct {
+	dir    string // the directory that must be given with -I, relative to the root path
+	header string // the header, relative to the root path
+}
+
+// commonDirectoryDepth returns how many leading directories two slash-separated paths have in common
+func commonDirectoryDepth(a, b string) int {
+	as, bs := strings.Split(path.Dir(a), "/"), strings.Split(path.Dir(b), "/")
+	n := 0
+	for n < len(as) && n < len(bs) && as[n] == bs[n] && as[n] != "." {
+		n++
+	}
+	return n
+}
+
+// InferIncludeDirectories finds the smallest set of -I directories, within the project, that makes every
+// #include line that matches a project header resolve, like "src/ui" for #include "widgets/button.h"
+// when there is a src/ui/widgets/button.h. Includes that are found next to the including file do not
+// need a directory. If locsys is not nil, <...> includes that are found in the system include
+// directories are skipped. When an include matches several headers, the header that is closest to the
+// including file is preferred, and then the header in a directory that is needed anyway.
+func (src *Sources) InferIncludeDirectories(locsys *LocalSystem) *IncludeDirectoryInference {
+	inference := &IncludeDirectoryInference{Directories: []string{}, Includes: []InferredInclude{}}
+	var headers []string
+	for _, name := range src.absFilenamesHeader {
+		headers = append(headers, src.fsPath(name))
+	}
+	var searchPath []string
+	if locsys != nil {
+		searchPath = src.searchPath(locsys)
+	}
+	candidatesFor := func(include string) []headerCandidate {
+		var candidates []headerCandidate
+		for _, header := range headers {
+			switch {
+			case header == include:
+				candidates = append(candidates, headerCandidate{dir: ".", header: header})
+			case strings.HasSuffix(header, "/"+include):
+				candidates = append(candidates, headerCandidate{dir: header[:len(header)-len(include)-1], header: header})
+			}
+		}
+		return candidates
+	}
+	type pending struct {
+		include    Include
+		file       string
+		candidates []headerCandidate
+	}
+	var (
+		ambiguous []pending
+		needed    []string
+	)
+	for _, include := range src.Includes() {
+		file := src.fsPath(include.File)
+		include.File = file
+		if !include.System {
+			if next := path.Join(path.Dir(file), include.Name); hasS(headers, next) {
+				inference.Includes = append(inference.Includes, InferredInclude{Include: include, Header: next})
+				continue
+			}
+		} else if locsys != nil {
+			if _, ok := src.findInSearchPath(locsys, searchPath, include.Name); ok {
+				continue
+			}
+		}
+		candidates := candidatesFor(include.Name)
+		switch len(candidates) {
+		case 0:
+			continue
+		case 1:
+			needed = appendUnique(needed, candidates[0].dir)
+			inference.Includes = append(inference.Includes, InferredInclude{Include: include, Header: candidates[0].header, Directory: candidates[0].dir})
+		default:
+			ambiguous = append(ambiguous, pending{include: include, file: file, candidates: candidates})
+		}
+	}
+	for _, p := range ambiguous {
+		best := -1
+		for i, candidate := range p.candidates {
+			if best == -1 {
+				best = i
+				continue
+			}
+			bestDepth, candidateDepth := commonDirectoryDepth(p.file, p.candidates[best].header), commonDirectoryDepth(p.file, candidate.header)
+			if candidateDepth != bestDepth {
+				if candidateDepth > bestDepth {
+					best = i
+				}
+				continue
+			}
+			if hasS(needed, candidate.dir) && !hasS(needed, p.candidates[best].dir) {
+				best = i
+			}
+		}
+		chosen := p.candidates[best]
+		needed = appendUnique(needed, chosen.dir)
+		var candidateHeaders []string
+		for _, candidate := range p.candidates {
+			candidateHeaders = append(candidateHeaders, candidate.header)
+		}
+		inference.Includes = append(inference.Includes, InferredInclude{Include: p.include, Header: chosen.header, Directory: chosen.dir, Candidates: candidateHeaders})
+		inference.Ambiguities = append(inference.Ambiguities, fmt.Sprintf("%s:%d: %s matches %s, chose %s", p.include.File, p.include.Line, p.include.Name, strings.Join(candidateHeaders, ", "), chosen.header))
+	}
+	sort.SliceStable(inference.Includes, func(i, j int) bool {
+		a, b := inference.Includes[i], inference.Includes[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	inference.Directories, inference.Conflicts = orderIncludeDirectories(needed, inference.Includes, headers)
+	return inference
+}
+
+// orderIncludeDirectories orders the directories so that each include is resolved to its header,
+// by placing the directory of each header before other directories that have a header with the same
+// include name. Includes that can not be resolved as intended with the resulting order are returned
+// as conflicts.
+func orderIncludeDirectories(dirs []string, includes []InferredInclude, headers []string) ([]string, []string) {
+	before := make(map[string]map[string]bool) // from a directory to the directories that must come after it
+	inDegree := make(map[string]int)
+	for _, include := range includes {
+		if include.Directory == "" {
+			continue
+		}
+		for _, dir := range dirs {
+			if dir == include.Directory || !hasS(headers, path.Join(dir, include.Name)) {
+				continue
+			}
+			if before[include.Directory] == nil {
+				before[include.Directory] = make(map[string]bool)
+			}
+			if !before[include.Directory][dir] {
+				before[include.Directory][dir] = true
+				inDegree[dir]++
+			}
+		}
+	}
+	// Kahn's algorithm, taking the directories in sorted order when there is a choice
+	var (
+		ordered   []string
+		remaining = append([]string{}, dirs...)
+	)
+	sort.Strings(remaining)
+	for len(remaining) > 0 {
+		next := -1
+		for i, dir := range remaining {
+			if inDegree[dir] == 0 {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			// There is a cycle, so take the next directory anyway, and report the includes that break
+			next = 0
+		}
+		dir := remaining[next]
+		remaining = append(remaining[:next], remaining[next+1:]...)
+		ordered = append(ordered, dir)
+		for after := range before[dir] {
+			inDegree[after]--
+		}
+	}
+	var conflicts []string
+	for _, include := range includes {
+		if include.Directory == "" {
+			continue
+		}
+		for _, dir := range ordered {
+			if !hasS(headers, path.Join(dir, include.Name)) {
+				continue
+			}
+			if dir != include.Directory {
+				conflicts = append(conflicts, fmt.Sprintf("%s:%d: %s resolves to %s instead of %s, since no order of the include directories works for all includes", include.File, include.Line, include.Name, path.Join(dir, include.Name), include.Header))
+			}
+			break
+		}
+	}
+	return ordered, conflicts
+}
+
+// findInSearchPath finds the given include in the system include directories
+func (src *Sources) findInSearchPath(locsys *LocalSystem, searchPath []string, include string) (string, bool) {
+	for _, includeDirectory := range searchPath {
+		if p := filepath.Join(includeDirectory, include); locsys.HasIncludeFile(p) {
+			return p, true
+		}
+	}
+	return "", false
+}
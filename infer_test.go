@@ -0,0 +1,72 @@
+package autocpp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestInferIncludeDirectories(t *testing.T) {
+	fsys := fstest.MapFS{
+		"src/app/main.cpp":          {Data: []byte("#include <stdio.h>\n#include \"widgets/button.h\"\n#include \"util.h\"\n#include \"config.h\"\n")},
+		"src/app/util.h":            {Data: []byte("#pragma once\n")},
+		"src/ui/widgets/button.h":   {Data: []byte("#pragma once\n#include \"widgets/label.h\"\n")},
+		"src/ui/widgets/label.h":    {Data: []byte("#pragma once\n")},
+		"src/app/config/config.h":   {Data: []byte("#pragma once\n")},
+		"third_party/lib/config.h":  {Data: []byte("#pragma once\n")},
+		"third_party/lib/unused.h":  {Data: []byte("#pragma once\n")},
+		"src/ui/widgets/button.cpp": {Data: []byte("#include \"button.h\"\n")},
+	}
+	src, err := NewSourcesWithOptions("project", SourcesOptions{FS: fsys, NoDetection: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inference := src.InferIncludeDirectories(newTestSystem(t))
+	if !reflect.DeepEqual(inference.Directories, []string{"src/app/config", "src/ui"}) {
+		t.Errorf("unexpected directories: %v", inference.Directories)
+	}
+	var resolved []string
+	for _, include := range inference.Includes {
+		resolved = append(resolved, include.File+":"+include.Name+"="+include.Header)
+	}
+	expected := []string{
+		"src/app/main.cpp:widgets/button.h=src/ui/widgets/button.h",
+		"src/app/main.cpp:util.h=src/app/util.h",
+		"src/app/main.cpp:config.h=src/app/config/config.h",
+		"src/ui/widgets/button.cpp:button.h=src/ui/widgets/button.h",
+		"src/ui/widgets/button.h:widgets/label.h=src/ui/widgets/label.h",
+	}
+	if !reflect.DeepEqual(resolved, expected) {
+		t.Errorf("unexpected includes:\n%s", strings.Join(resolved, "\n"))
+	}
+	if len(inference.Ambiguities) != 1 || !strings.Contains(inference.Ambiguities[0], "chose src/app/config/config.h") {
+		t.Errorf("unexpected ambiguities: %v", inference.Ambiguities)
+	}
+	if len(inference.Conflicts) != 0 {
+		t.Errorf("unexpected conflicts: %v", inference.Conflicts)
+	}
+}
+
+func TestInferIncludeDirectoriesConflict(t *testing.T) {
+	fsys := fstest.MapFS{
+		"one/sub/a.cpp":  {Data: []byte("#include \"x/common.h\"\n")},
+		"one/x/common.h": {Data: []byte("#pragma once\n")},
+		"two/sub/b.cpp":  {Data: []byte("#include \"x/common.h\"\n")},
+		"two/x/common.h": {Data: []byte("#pragma once\n")},
+	}
+	src, err := NewSourcesWithOptions("project", SourcesOptions{FS: fsys, NoDetection: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inference := src.InferIncludeDirectories(nil)
+	if !reflect.DeepEqual(inference.Directories, []string{"one", "two"}) {
+		t.Errorf("unexpected directories: %v", inference.Directories)
+	}
+	if len(inference.Ambiguities) != 2 {
+		t.Errorf("expected two ambiguities, got: %v", inference.Ambiguities)
+	}
+	if len(inference.Conflicts) != 1 || !strings.HasPrefix(inference.Conflicts[0], "two/sub/b.cpp:1: x/common.h resolves to one/x/common.h") {
+		t.Errorf("unexpected conflicts: %v", inference.Conflicts)
+	}
+}
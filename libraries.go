@@ -0,0 +1,105 @@
+package autocpp
+
+import (
+	"sort"
+	"strings"
+)
+
+// knownLibraries maps short include names, or prefixes of short include names that end with "/",
+// to the pkg-config names of the libraries that provide them
+var knownLibraries = map[string]string{
+	"SDL2/":            "sdl2",
+	"SDL.h":            "sdl",
+	"SDL3/":            "sdl3",
+	"GL/glew.h":        "glew",
+	"GL/gl.h":          "gl",
+	"GL/glu.h":         "glu",
+	"GL/glut.h":        "glut",
+	"GLFW/":            "glfw3",
+	"X11/":             "x11",
+	"vulkan/":          "vulkan",
+	"png.h":            "libpng",
+	"jpeglib.h":        "libjpeg",
+	"zlib.h":           "zlib",
+	"bzlib.h":          "bzip2",
+	"lzma.h":           "liblzma",
+	"zstd.h":           "libzstd",
+	"curl/":            "libcurl",
+	"openssl/":         "openssl",
+	"sqlite3.h":        "sqlite3",
+	"libxml/":          "libxml-2.0",
+	"gtk/":             "gtk+-3.0",
+	"glib.h":           "glib-2.0",
+	"cairo.h":          "cairo",
+	"ft2build.h":       "freetype2",
+	"fontconfig/":      "fontconfig",
+	"pango/":           "pango",
+	"AL/":              "openal",
+	"sndfile.h":        "sndfile",
+	"alsa/":            "alsa",
+	"pulse/":           "libpulse",
+	"yaml-cpp/":        "yaml-cpp",
+	"fmt/":             "fmt",
+	"spdlog/":          "spdlog",
+	"gtest/":           "gtest",
+	"gmock/":           "gmock",
+	"sigc++/":          "sigc++-2.0",
+	"QtCore/":          "Qt5Core",
+	"QtGui/":           "Qt5Gui",
+	"QtWidgets/":       "Qt5Widgets",
+	"boost/filesystem": "boost_filesystem",
+	"readline/":        "readline",
+	"ncurses.h":        "ncurses",
+	"curses.h":         "ncurses",
+	"uuid/":            "uuid",
+	"systemd/":         "libsystemd",
+	"dbus/":            "dbus-1",
+	"libusb-1.0/":      "libusb-1.0",
+	"portaudio.h":      "portaudio-2.0",
+	"lua.h":            "lua",
+	"Python.h":         "python3",
+}
+
+// libraryFor returns the pkg-config name of the library that provides the given short include name,
+// or an empty string if it is not known
+func libraryFor(include string) string {
+	if library, ok := knownLibraries[include]; ok && !strings.HasSuffix(include, "/") {
+		return library
+	}
+	for prefix, library := range knownLibraries {
+		if strings.HasSuffix(prefix, "/") && strings.HasPrefix(include, prefix) {
+			return library
+		}
+	}
+	return ""
+}
+
+// Libraries returns the sorted pkg-config names of the known libraries that the includes of the sources need,
+// like "sdl2" for <SDL2/SDL.h>
+func (src *Sources) Libraries() []string {
+	var libraries []string
+	for _, include := range src.ShortIncludes() {
+		if library := libraryFor(include); library != "" && !hasS(libraries, library) {
+			libraries = append(libraries, library)
+		}
+	}
+	sort.Strings(libraries)
+	return libraries
+}
+
+// LinkFlags returns the linker flags that are needed for the system libraries that are not provided
+// by pkg-config, like "-pthread" for <thread> and "-lm" for <math.h> in C
+func (src *Sources) LinkFlags() []string {
+	var flags []string
+	if src.Thread() {
+		flags = append(flags, "-pthread")
+	}
+	shortIncludes := src.ShortIncludes()
+	if hasS(shortIncludes, "dlfcn.h") {
+		flags = append(flags, "-ldl")
+	}
+	if len(src.absFilenamesC) > 0 && (hasS(shortIncludes, "math.h") || hasS(shortIncludes, "complex.h")) {
+		flags = append(flags, "-lm")
+	}
+	return flags
+}
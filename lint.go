@@ -0,0 +1,165 @@
+package autocpp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The rules of LintIncludes
+const (
+	LintIncludeOrder      = "include-order"
+	LintIncludeGrouping   = "include-grouping"
+	LintDuplicateInclude  = "duplicate-include"
+	LintAngleBrackets     = "angle-brackets-for-project-header"
+	LintQuotes            = "quotes-for-system-header"
+	LintDeprecatedCHeader = "deprecated-c-header"
+)
+
+// LintRules are the names of all rules of LintIncludes
+var LintRules = []string{LintIncludeOrder, LintIncludeGrouping, LintDuplicateInclude, LintAngleBrackets, LintQuotes, LintDeprecatedCHeader}
+
+// DefaultIncludeOrder is the order of the include categories that is checked by the include-order rule:
+// the own header first, then C system headers, C++ standard headers, third-party headers and project headers
+var DefaultIncludeOrder = []IncludeCategory{CategoryOwnHeader, CategoryCSystem, CategoryCXXStandard, CategoryThirdParty, CategoryProject}
+
+// ParseIncludeCategory parses a category name, like "c-system"
+func ParseIncludeCategory(s string) (IncludeCategory, error) {
+	for i, name := range includeCategoryNames {
+		if strings.EqualFold(s, name) {
+			return IncludeCategory(i), nil
+		}
+	}
+	return CategoryProject, fmt.Errorf("unknown include category %q, expected one of: %s", s, strings.Join(includeCategoryNames, ", "))
+}
+
+// LintOptions can be used for configuring LintIncludes
+type LintOptions struct {
+	// Disabled are the names of the rules that are not checked
+	Disabled []string
+	// Order is the order of the include categories. Categories that are not listed can come anywhere.
+	// If nil, DefaultIncludeOrder is used.
+	Order []IncludeCategory
+	// LocalSystem is used for finding out if an include is a system header. If nil, only the standard
+	// headers are known to be system headers.
+	LocalSystem *LocalSystem
+}
+
+// LintIssue is a problem with an #include line
+type LintIssue struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	// Suggestion is the #include line that should be used instead, if any
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// String returns the issue as "file:line: message [rule]"
+func (issue LintIssue) String() string {
+	return fmt.Sprintf("%s:%d: %s [%s]", issue.File, issue.Line, issue.Message, issue.Rule)
+}
+
+// LintIncludes checks the #include lines of all source files for the order of the include categories,
+// blank lines between the groups of categories, duplicate includes, <...> used for project headers,
+// "..." used for system headers and deprecated C headers in C++, like <stdio.h> instead of <cstdio>.
+// The order and grouping rules only look at includes outside of #if blocks. The issues are ordered
+// by file, then by line and then by rule.
+func (src *Sources) LintIncludes(opts LintOptions) []LintIssue {
+	enabled := make(map[string]bool)
+	for _, rule := range LintRules {
+		enabled[rule] = !hasS(opts.Disabled, rule)
+	}
+	order := opts.Order
+	if order == nil {
+		order = DefaultIncludeOrder
+	}
+	rank := make(map[IncludeCategory]int)
+	for i, category := range order {
+		rank[category] = i + 1
+	}
+	var searchPath []string
+	if opts.LocalSystem != nil {
+		searchPath = src.searchPath(opts.LocalSystem)
+	}
+	isSystemHeader := func(name string) bool {
+		if isCSystemHeader(name) || isCXXStandardHeader(name) {
+			return true
+		}
+		if opts.LocalSystem == nil {
+			return false
+		}
+		_, ok := src.findInSearchPath(opts.LocalSystem, searchPath, name)
+		return ok
+	}
+	var issues []LintIssue
+	add := func(include Include, rule, message, suggestion string) {
+		if enabled[rule] {
+			issues = append(issues, LintIssue{File: include.File, Line: include.Line, Rule: rule, Message: message, Suggestion: suggestion})
+		}
+	}
+	for _, filename := range src.AllFilenames() {
+		f, ok := src.files[filename]
+		if !ok || len(f.includes) == 0 {
+			continue
+		}
+		conditional := conditionalLines(f.data)
+		lang := src.fileLanguage(filename)
+		seen := make(map[string]Include)
+		var previous *Include // the previous include outside of #if blocks
+		latest := -1          // the index of the latest category so far, in the order
+		for i, include := range f.includes {
+			include := include
+			if first, ok := seen[include.String()]; ok && !conditional[include.Line] && !conditional[first.Line] {
+				add(include, LintDuplicateInclude, fmt.Sprintf("%s is already included on line %d", include.Name, first.Line), "")
+			} else if !ok {
+				seen[include.String()] = include
+			}
+			_, isProjectHeader := src.ResolveProjectInclude(include)
+			if include.System && isProjectHeader && !isSystemHeader(include.Name) {
+				add(include, LintAngleBrackets, fmt.Sprintf("%s is a project header, but is included with <...>", include.Name), Include{Name: include.Name}.String())
+			} else if !include.System && !isProjectHeader && isSystemHeader(include.Name) {
+				add(include, LintQuotes, fmt.Sprintf("%s is a system header, but is included with \"...\"", include.Name), Include{Name: include.Name, System: true}.String())
+			}
+			if cxxHeader, ok := cxxHeadersForC[include.Name]; ok && lang == LanguageCXX && include.System {
+				add(include, LintDeprecatedCHeader, fmt.Sprintf("%s is a deprecated C header in C++, use <%s>", include.Name, cxxHeader), Include{Name: cxxHeader, System: true}.String())
+			}
+			if conditional[include.Line] {
+				continue
+			}
+			category := CategoryOf(include)
+			if category == CategoryOwnHeader && i > 0 {
+				// The own header only counts as such when it comes first
+				category = CategoryProject
+			}
+			if r, ok := rank[category]; ok {
+				if r < latest {
+					add(include, LintIncludeOrder, fmt.Sprintf("%s (%s) should come before the %s includes", include.Name, category, order[latest-1]), "")
+				} else {
+					latest = r
+				}
+			}
+			if previous != nil && previous.Line == include.Line-1 {
+				previousCategory := CategoryOf(*previous)
+				if previousCategory == CategoryOwnHeader && previous.Line != f.includes[0].Line {
+					previousCategory = CategoryProject
+				}
+				if previousCategory != category {
+					add(include, LintIncludeGrouping, fmt.Sprintf("%s (%s) should be separated from the %s includes by a blank line", include.Name, category, previousCategory), "")
+				}
+			}
+			previous = &f.includes[i]
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Rule < b.Rule
+	})
+	return issues
+}
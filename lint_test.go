@@ -0,0 +1,84 @@
+package autocpp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const lintTestMain = `#include "main.h"
+#include <stdio.h>
+
+#include "widget.h"
+
+#include <vector>
+#include <SDL2/SDL.h>
+#include <util.h>
+#include "string"
+#include <vector>
+#ifdef DEBUG
+#include "debug.h"
+#include "widget.h"
+#endif
+`
+
+func TestLintIncludes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.cpp": {Data: []byte(lintTestMain)},
+		"main.h":   {Data: []byte("#pragma once\n")},
+		"widget.h": {Data: []byte("#pragma once\n")},
+		"util.h":   {Data: []byte("#pragma once\n")},
+		"debug.h":  {Data: []byte("#pragma once\n")},
+		"sorted.c": {Data: []byte("#include \"sorted.h\"\n\n#include <stdio.h>\n\n#include \"util.h\"\n")},
+		"sorted.h": {Data: []byte("#pragma once\n")},
+	}
+	src, err := NewSourcesWithOptions("project", SourcesOptions{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []string
+	for _, issue := range src.LintIncludes(LintOptions{}) {
+		results = append(results, strings.Join([]string{issue.String(), issue.Suggestion}, "|"))
+	}
+	expected := []string{
+		"project/main.cpp:2: stdio.h is a deprecated C header in C++, use <cstdio> [deprecated-c-header]|#include <cstdio>",
+		"project/main.cpp:2: stdio.h (c-system) should be separated from the own-header includes by a blank line [include-grouping]|",
+		"project/main.cpp:6: vector (c++-standard) should come before the project includes [include-order]|",
+		"project/main.cpp:7: SDL2/SDL.h (third-party) should be separated from the c++-standard includes by a blank line [include-grouping]|",
+		"project/main.cpp:7: SDL2/SDL.h (third-party) should come before the project includes [include-order]|",
+		"project/main.cpp:8: util.h is a project header, but is included with <...> [angle-brackets-for-project-header]|#include \"util.h\"",
+		"project/main.cpp:8: util.h (third-party) should come before the project includes [include-order]|",
+		"project/main.cpp:9: string (project) should be separated from the third-party includes by a blank line [include-grouping]|",
+		"project/main.cpp:9: string is a system header, but is included with \"...\" [quotes-for-system-header]|#include <string>",
+		"project/main.cpp:10: vector is already included on line 6 [duplicate-include]|",
+		"project/main.cpp:10: vector (c++-standard) should be separated from the project includes by a blank line [include-grouping]|",
+		"project/main.cpp:10: vector (c++-standard) should come before the project includes [include-order]|",
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("unexpected issues:\n%s", strings.Join(results, "\n"))
+	}
+	// The rules can be disabled, and the order of the categories can be changed
+	issues := src.LintIncludes(LintOptions{
+		Disabled: []string{LintIncludeGrouping, LintDeprecatedCHeader, LintAngleBrackets, LintQuotes, LintDuplicateInclude},
+		Order:    []IncludeCategory{CategoryOwnHeader, CategoryProject, CategoryCSystem},
+	})
+	results = nil
+	for _, issue := range issues {
+		results = append(results, issue.String())
+	}
+	expected = []string{
+		"project/main.cpp:4: widget.h (project) should come before the c-system includes [include-order]",
+		"project/main.cpp:9: string (project) should come before the c-system includes [include-order]",
+		"project/sorted.c:5: util.h (project) should come before the c-system includes [include-order]",
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("unexpected issues: %+v", issues)
+	}
+	if category, err := ParseIncludeCategory("C++-Standard"); err != nil || category != CategoryCXXStandard {
+		t.Errorf("unexpected category %v: %v", category, err)
+	}
+	if _, err := ParseIncludeCategory("standard"); err == nil {
+		t.Errorf("expected an error for an unknown category")
+	}
+}
@@ -0,0 +1,145 @@
+package autocpp
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// cleanLocalDirectory returns a clean slash-separated directory, relative to the root path
+func cleanLocalDirectory(dir string) string {
+	return path.Clean(filepath.ToSlash(dir))
+}
+
+// AddLocalIncludeDirectories adds directories relative to the root path, like "src/include",
+// that are searched after the ones that were given with SourcesOptions and before the detected ones
+func (src *Sources) AddLocalIncludeDirectories(dirs ...string) {
+	for _, dir := range dirs {
+		dir = cleanLocalDirectory(dir)
+		src.localIncludeDirectories = appendUnique(src.localIncludeDirectories, dir)
+		src.removedLocalIncludeDirectories = subtractS(src.removedLocalIncludeDirectories, []string{dir})
+	}
+}
+
+// RemoveLocalIncludeDirectories makes sure that the given directories, relative to the root path,
+// are not searched, even if they are detected or are one of the default local include directories
+func (src *Sources) RemoveLocalIncludeDirectories(dirs ...string) {
+	for _, dir := range dirs {
+		src.removedLocalIncludeDirectories = appendUnique(src.removedLocalIncludeDirectories, cleanLocalDirectory(dir))
+	}
+}
+
+// LocalIncludeDirectories returns the directories, relative to the root path, that are searched for
+// includes that are not in a system include directory. The directories that were given come first,
+// then the detected ones and then the default ones, like "include" and "../common".
+func (src *Sources) LocalIncludeDirectories() []string {
+	return src.localIncludeDirectoriesFor(nil)
+}
+
+// localIncludeDirectoriesFor is like LocalIncludeDirectories, but uses the relative local include
+// directories of the given LocalSystem instead of the default ones, if it is not nil
+func (src *Sources) localIncludeDirectoriesFor(locsys *LocalSystem) []string {
+	dirs := append([]string{}, src.localIncludeDirectories...)
+	if src.detect {
+		dirs = appendUnique(dirs, src.DetectLocalIncludeDirectories()...)
+	}
+	defaults := defaultLocalIncludeDirectories
+	if locsys != nil {
+		defaults = locsys.localIncludeDirectories
+	}
+	for _, dir := range defaults {
+		if !filepath.IsAbs(dir) {
+			dirs = appendUnique(dirs, cleanLocalDirectory(dir))
+		}
+	}
+	return subtractS(dirs, src.removedLocalIncludeDirectories)
+}
+
+// DetectLocalIncludeDirectories returns the directories within the project, relative to the root path,
+// that contain headers that the project includes, like "src/include" for #include "app/config.h" when
+// there is a src/include/app/config.h. The directories that resolve the most includes come first.
+func (src *Sources) DetectLocalIncludeDirectories() []string {
+	count := make(map[string]int)
+	for _, include := range src.ShortIncludes() {
+		for _, header := range src.absFilenamesHeader {
+			p := src.fsPath(header)
+			switch {
+			case p == include:
+				count["."]++
+			case strings.HasSuffix(p, "/"+include):
+				count[p[:len(p)-len(include)-1]]++
+			}
+		}
+	}
+	dirs := make([]string, 0, len(count))
+	for dir := range count {
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if count[dirs[i]] != count[dirs[j]] {
+			return count[dirs[i]] > count[dirs[j]]
+		}
+		return dirs[i] < dirs[j]
+	})
+	return dirs
+}
+
+// localFile checks if the given slash-separated path, relative to the root path, exists, and returns
+// its filename. Paths outside of the root path, like "../common/util.h", can only be found if the
+// sources are read from the real file system.
+func (src *Sources) localFile(p string) (string, bool) {
+	p = path.Clean(p)
+	if p != ".." && !strings.HasPrefix(p, "../") {
+		if _, err := fs.Stat(src.fsys, p); err == nil {
+			return src.name(p), true
+		}
+		return "", false
+	}
+	if !src.osFS {
+		return "", false
+	}
+	name := filepath.Join(src.rootPath, filepath.FromSlash(p))
+	if _, err := os.Stat(name); err == nil {
+		return name, true
+	}
+	return "", false
+}
+
+// nextToIncludingFile is a header that was found next to a file that includes it with "..."
+type nextToIncludingFile struct {
+	path          string
+	includingFile string
+}
+
+// findNextToIncludingFiles finds the files for "..." includes in the directories of the files that include them,
+// like compilers do before searching any include directories. Since the results are per include name, a name is
+// only kept if every file that includes it uses "..." and has the header next to it. Otherwise, other files would
+// find a different header, like a local stdio.h that would shadow <stdio.h> for the whole project.
+func (src *Sources) findNextToIncludingFiles() map[string]nextToIncludingFile {
+	found := make(map[string]nextToIncludingFile)
+	ambiguous := make(map[string]bool)
+	for _, record := range src.Includes() {
+		if ambiguous[record.Name] {
+			continue
+		}
+		if record.System {
+			ambiguous[record.Name] = true
+			continue
+		}
+		name, ok := src.localFile(path.Join(path.Dir(src.fsPath(record.File)), record.Name))
+		if !ok {
+			ambiguous[record.Name] = true
+			continue
+		}
+		if _, ok := found[record.Name]; !ok {
+			found[record.Name] = nextToIncludingFile{path: name, includingFile: record.File}
+		}
+	}
+	for name := range ambiguous {
+		delete(found, name)
+	}
+	return found
+}
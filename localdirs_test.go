@@ -0,0 +1,120 @@
+package autocpp
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDetectLocalIncludeDirectories(t *testing.T) {
+	fsys := fstest.MapFS{
+		"src/main.cpp":                 {Data: []byte("#include \"widgets/button.h\"\n#include \"widgets/label.h\"\n#include \"config.h\"\n")},
+		"src/ui/widgets/button.h":      {Data: []byte("#pragma once\n")},
+		"src/ui/widgets/label.h":       {Data: []byte("#pragma once\n")},
+		"third_party/cfg/config.h":     {Data: []byte("#pragma once\n")},
+		"third_party/unused/unused.h":  {Data: []byte("#pragma once\n")},
+		"tests/widgets/button_test.cc": {Data: []byte("#include \"widgets/button.h\"\n")},
+	}
+	src, err := NewSourcesWithOptions("project", SourcesOptions{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dirs := src.DetectLocalIncludeDirectories(); !reflect.DeepEqual(dirs, []string{"src/ui", "third_party/cfg"}) {
+		t.Errorf("unexpected detected directories: %v", dirs)
+	}
+	if notFound := src.FindIncludePaths(newTestSystem(t)); len(notFound) != 0 {
+		t.Errorf("expected all includes to be found, but these were not: %v", notFound)
+	}
+	if resolution, _ := src.Resolution("widgets/button.h"); resolution.Path != filepath.Join("project", "src", "ui", "widgets", "button.h") || resolution.Rule != RuleProjectIncludeDirectory {
+		t.Errorf("unexpected resolution: %+v", resolution)
+	}
+
+	src.RemoveLocalIncludeDirectories("third_party/cfg/")
+	src.AddLocalIncludeDirectories("include")
+	dirs := src.LocalIncludeDirectories()
+	if dirs[0] != "include" || dirs[1] != "src/ui" || hasS(dirs, "third_party/cfg") || !hasS(dirs, "..") {
+		t.Errorf("unexpected local include directories: %v", dirs)
+	}
+	if notFound := src.FindIncludePaths(newTestSystem(t)); !reflect.DeepEqual(notFound, []string{"config.h"}) {
+		t.Errorf("expected config.h to no longer be found, got %v", notFound)
+	}
+
+	src, err = NewSourcesWithOptions("project", SourcesOptions{FS: fsys, NoDetection: true, RemoveLocalIncludeDirectories: []string{".."}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dirs := src.LocalIncludeDirectories(); hasS(dirs, "src/ui") || hasS(dirs, "..") || !hasS(dirs, "include") {
+		t.Errorf("unexpected local include directories without detection: %v", dirs)
+	}
+}
+
+func TestIncludingFileDirectory(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/main.cpp": {Data: []byte("#include \"stdio.h\"\n#include \"helper.h\"\n")},
+		"a/stdio.h":  {Data: []byte("#pragma once\n")},
+		"b/helper.h": {Data: []byte("#pragma once\n")},
+	}
+	src, err := NewSourcesWithOptions("project", SourcesOptions{FS: fsys, NoDetection: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.FindIncludePaths(newTestSystem(t))
+	if resolution, _ := src.Resolution("stdio.h"); resolution.Path != filepath.Join("project", "a", "stdio.h") || resolution.Rule != RuleIncludingFileDirectory {
+		t.Errorf("expected the header next to main.cpp to win over the system header, got %+v", resolution)
+	}
+	if _, ok := src.Resolution("helper.h"); !ok {
+		t.Errorf("expected a resolution for helper.h")
+	}
+	if path, ok := src.IncludePath("helper.h"); ok {
+		t.Errorf("expected helper.h to not be found without detection, got %s", path)
+	}
+	// A header next to one file must not shadow the system header for the other files
+	fsys["c/other.c"] = &fstest.MapFile{Data: []byte("#include <stdio.h>\n")}
+	src, err = NewSourcesWithOptions("project", SourcesOptions{FS: fsys, NoDetection: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.FindIncludePaths(newTestSystem(t))
+	if resolution, _ := src.Resolution("stdio.h"); resolution.Rule != RuleSystemIncludeDirectory {
+		t.Errorf("expected stdio.h to be found in the system include directory, got %+v", resolution)
+	}
+	main := src.FileIncludes(filepath.Join("project", "a", "main.cpp"))
+	if target, ok := src.ResolveProjectInclude(main[0]); !ok || target != filepath.Join("project", "a", "stdio.h") {
+		t.Errorf("expected the header next to main.cpp for its own include, got %q", target)
+	}
+}
+
+func TestLocalIncludeDirectoriesIndependentOfWorkingDirectory(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, "project/main.cpp", "common/shared.h", "project/include/app.h")
+	if err := os.WriteFile(filepath.Join(root, "project", "main.cpp"), []byte("#include \"shared.h\"\n#include \"app.h\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	find := func() map[string]string {
+		src, err := NewSources(filepath.Join(root, "project"), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if notFound := src.FindIncludePaths(newTestSystem(t)); len(notFound) != 0 {
+			t.Errorf("expected all includes to be found, but these were not: %v", notFound)
+		}
+		return src.foundMap
+	}
+	fromHere := find()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if fromRoot := find(); !reflect.DeepEqual(fromHere, fromRoot) {
+		t.Errorf("expected the same includes from a different working directory, got %v and %v", fromHere, fromRoot)
+	}
+	if fromHere["shared.h"] != filepath.Join(root, "common", "shared.h") {
+		t.Errorf("expected shared.h to be found in ../common, got %s", fromHere["shared.h"])
+	}
+}
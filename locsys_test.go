@@ -1,10 +1,41 @@
 package autocpp
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"reflect"
 	"testing"
+	"testing/fstest"
 )
 
+// testSystemFS is a small file system with system include files, rooted at "/"
+var testSystemFS = fstest.MapFS{
+	"usr/include/stdio.h":                        {Data: []byte("int printf(const char *format, ...);\n")},
+	"usr/include/stdint.h":                       {Data: []byte("typedef unsigned int uint32_t;\n")},
+	"usr/include/math.h":                         {Data: []byte("double sqrt(double x);\n")},
+	"usr/include/c++/12/iostream":                {Data: []byte("#include <ostream>\n")},
+	"usr/include/c++/12/ostream":                 {Data: []byte("#include <ios>\n")},
+	"usr/include/c++/12/ios":                     {Data: []byte("\n")},
+	"usr/include/c++/12/thread":                  {Data: []byte("\n")},
+	"usr/include/c++/12/vector":                  {Data: []byte("\n")},
+	"usr/include/c++/12/cstdint":                 {Data: []byte("#include <stdint.h>\n")},
+	"usr/include/c++/12/cmath":                   {Data: []byte("#include <math.h>\n")},
+	"usr/include/x86_64-linux-gnu/c++/12/vector": {Data: []byte("\n")},
+	"usr/include/SDL2/SDL.h":                     {Data: []byte("int SDL_Init(unsigned int flags);\n")},
+	"usr/share/doc/README":                       {Data: []byte("\n")},
+}
+
+// newTestSystem returns a LocalSystem that uses testSystemFS instead of the real file system
+func newTestSystem(t testing.TB) *LocalSystem {
+	t.Helper()
+	locsys, err := NewLocalSystemWithOptions(LocalSystemOptions{FS: testSystemFS, IgnoreEnvironment: true, SystemIncludeDirectories: []string{"/usr/include"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return locsys
+}
+
 // an alternative to an "init" function, for initializing a new LocalSystem
 var locsys = func() *LocalSystem {
 	locsys, err := NewLocalSystem(true)
@@ -17,3 +48,55 @@ var locsys = func() *LocalSystem {
 func TestLocSysIncludes(t *testing.T) {
 	fmt.Println(locsys.IncludeFiles())
 }
+
+func TestLocSysIncludesFS(t *testing.T) {
+	locsys := newTestSystem(t)
+	for _, expected := range []string{"/usr/include/stdio.h", "/usr/include/c++/12/iostream", "/usr/include/c++/12/vector", "/usr/include/SDL2/SDL.h"} {
+		if !hasS(locsys.IncludeFiles(), expected) {
+			t.Errorf("expected %s to be found, got %v", expected, locsys.IncludeFiles())
+		}
+	}
+	if hasS(locsys.IncludeFiles(), "/usr/share/doc/README") {
+		t.Errorf("did not expect files outside of the system include directories to be found")
+	}
+	if !locsys.Exists("/usr/include/SDL2") || locsys.Exists("/usr/include/SDL3") {
+		t.Errorf("Exists does not use the given file system")
+	}
+}
+
+func TestLocSysDeterministic(t *testing.T) {
+	first := newTestSystem(t).IncludeFiles()
+	for i := 0; i < 10; i++ {
+		if again := newTestSystem(t).IncludeFiles(); !reflect.DeepEqual(first, again) {
+			t.Fatalf("the include files were found in a different order: %v and %v", first, again)
+		}
+	}
+}
+
+func TestLocSysNested(t *testing.T) {
+	locsys, err := NewLocalSystemWithOptions(LocalSystemOptions{FS: testSystemFS, IgnoreEnvironment: true, SystemIncludeDirectories: []string{"/usr/include/c++/12", "/usr/include"}, Concurrency: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, includeFile := range locsys.IncludeFiles() {
+		if seen[includeFile] {
+			t.Errorf("%s was found twice", includeFile)
+		}
+		seen[includeFile] = true
+	}
+	if locsys.IncludeFiles()[0] != "/usr/include/c++/12/cmath" {
+		t.Errorf("expected the first system include directory to be searched first, got %v", locsys.IncludeFiles())
+	}
+}
+
+func TestLocSysCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewLocalSystemContext(ctx, LocalSystemOptions{FS: testSystemFS, IgnoreEnvironment: true}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if _, err := NewSourcesContext(ctx, exampleProjectDirectory, SourcesOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package autocpp
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// memFS is a read-only file system that is kept in memory, for the contents of archives
+type memFS struct {
+	files   map[string][]byte
+	modTime map[string]time.Time
+	dirs    map[string][]string // from a directory to the base names of its entries
+}
+
+func newMemFS() *memFS {
+	return &memFS{
+		files:   make(map[string][]byte),
+		modTime: make(map[string]time.Time),
+		dirs:    map[string][]string{".": nil},
+	}
+}
+
+// add adds a file with the given slash-separated path and contents, and all of its parent directories
+func (mfs *memFS) add(name string, data []byte, modTime time.Time) {
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+	if !fs.ValidPath(name) || name == "." {
+		return
+	}
+	if _, ok := mfs.files[name]; !ok {
+		child := name
+		for dir := path.Dir(name); ; dir = path.Dir(dir) {
+			_, existed := mfs.dirs[dir]
+			if !hasS(mfs.dirs[dir], path.Base(child)) {
+				mfs.dirs[dir] = append(mfs.dirs[dir], path.Base(child))
+			}
+			if existed || dir == "." {
+				break
+			}
+			child = dir
+		}
+	}
+	mfs.files[name] = data
+	mfs.modTime[name] = modTime
+}
+
+func (mfs *memFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if data, ok := mfs.files[name]; ok {
+		return &memFile{info: memFileInfo{name: path.Base(name), size: int64(len(data)), modTime: mfs.modTime[name]}, Reader: bytes.NewReader(data)}, nil
+	}
+	if _, ok := mfs.dirs[name]; ok {
+		entries, _ := mfs.ReadDir(name)
+		return &memDir{info: memFileInfo{name: path.Base(name), dir: true}, entries: entries}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (mfs *memFS) ReadFile(name string) ([]byte, error) {
+	data, ok := mfs.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]byte{}, data...), nil
+}
+
+func (mfs *memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	names, ok := mfs.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+	entries := make([]fs.DirEntry, 0, len(sorted))
+	for _, base := range sorted {
+		p := path.Join(name, base)
+		if data, ok := mfs.files[p]; ok {
+			entries = append(entries, fs.FileInfoToDirEntry(memFileInfo{name: base, size: int64(len(data)), modTime: mfs.modTime[p]}))
+		} else {
+			entries = append(entries, fs.FileInfoToDirEntry(memFileInfo{name: base, dir: true}))
+		}
+	}
+	return entries, nil
+}
+
+type memFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (fi memFileInfo) Name() string       { return fi.name }
+func (fi memFileInfo) Size() int64        { return fi.size }
+func (fi memFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi memFileInfo) IsDir() bool        { return fi.dir }
+func (fi memFileInfo) Sys() interface{}   { return nil }
+
+func (fi memFileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+type memFile struct {
+	info memFileInfo
+	*bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Close() error               { return nil }
+
+type memDir struct {
+	info    memFileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+	return remaining[:n], nil
+}
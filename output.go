@@ -0,0 +1,25 @@
+package autocpp
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// verboseOutput returns the io.Writer that verbose output should be written to, or nil if verbose is false
+func verboseOutput(verbose bool, w io.Writer) io.Writer {
+	if !verbose {
+		return nil
+	}
+	if w == nil {
+		return os.Stdout
+	}
+	return w
+}
+
+// logf writes verbose output to w, if w is not nil
+func logf(w io.Writer, format string, args ...interface{}) {
+	if w != nil {
+		fmt.Fprintf(w, format, args...)
+	}
+}
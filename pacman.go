@@ -1,8 +1,33 @@
 package autocpp
 
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
 // Pacman implements the PackageSystem interface
 
 type Pacman struct {
+	fsys   fs.FS  // rooted at "/"
+	root   string // the root directory that the packages are installed in
+	dbPath string // the pacman database, typically /var/lib/pacman
+}
+
+// NewPacman returns a Pacman that uses the package database within the given root directory.
+// An empty root directory means "/".
+func NewPacman(rootDirectory string) *Pacman {
+	return newPacmanFS(os.DirFS("/"), rootDirectory)
+}
+
+// newPacmanFS is like NewPacman, but reads the package database from the given file system, rooted at "/"
+func newPacmanFS(fsys fs.FS, rootDirectory string) *Pacman {
+	if rootDirectory == "" {
+		rootDirectory = "/"
+	}
+	return &Pacman{fsys: fsys, root: rootDirectory, dbPath: filepath.Join(rootDirectory, "var", "lib", "pacman")}
 }
 
 func (pacman *Pacman) PackagesProvides(shortIncludeName string) ([]string, error) {
@@ -12,3 +37,69 @@ func (pacman *Pacman) PackagesProvides(shortIncludeName string) ([]string, error
 func (pacman *Pacman) IncludePathToCXXFlags(string) string {
 	return ""
 }
+
+// DatabasePath returns the directory with the local package database
+func (pacman *Pacman) DatabasePath() string {
+	return filepath.Join(pacman.dbPath, "local")
+}
+
+// Owners returns a map from the absolute paths of all installed files to the names of the packages that own them
+func (pacman *Pacman) Owners() (map[string]string, error) {
+	localDir := rootedPath(pacman.DatabasePath())
+	entries, err := fs.ReadDir(pacman.fsys, localDir)
+	if err != nil {
+		return nil, err
+	}
+	owners := make(map[string]string)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		packageName := ""
+		if desc, err := fs.ReadFile(pacman.fsys, path.Join(localDir, entry.Name(), "desc")); err == nil {
+			if names := pacmanSection(desc, "%NAME%"); len(names) > 0 {
+				packageName = names[0]
+			}
+		}
+		if packageName == "" {
+			continue
+		}
+		files, err := fs.ReadFile(pacman.fsys, path.Join(localDir, entry.Name(), "files"))
+		if err != nil {
+			continue
+		}
+		for _, filename := range pacmanSection(files, "%FILES%") {
+			if !strings.HasSuffix(filename, "/") {
+				owners[filepath.Join(pacman.root, filepath.FromSlash(filename))] = packageName
+			}
+		}
+	}
+	return owners, nil
+}
+
+// pacmanSection returns the lines of the given section, like "%FILES%", in a pacman database file
+func pacmanSection(data []byte, section string) []string {
+	var lines []string
+	inSection := false
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == section:
+			inSection = true
+		case strings.HasPrefix(line, "%") && strings.HasSuffix(line, "%"):
+			inSection = false
+		case inSection && line != "":
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// rootedPath returns the slash-separated path within a file system rooted at "/", for the given absolute path
+func rootedPath(name string) string {
+	name = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(name)), filepath.ToSlash(filepath.VolumeName(name)))
+	if name = strings.TrimPrefix(name, "/"); name == "" {
+		return "."
+	}
+	return name
+}
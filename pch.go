@@ -0,0 +1,205 @@
+package autocpp
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultPCHFilename is the name of the precompiled header that SuggestPrecompiledHeader proposes
+const DefaultPCHFilename = "pch.hpp"
+
+// PCHOptions can be used for configuring SuggestPrecompiledHeader
+type PCHOptions struct {
+	// MinFrequency is the fraction of the translation units that must include a header, for the header
+	// to be part of the precompiled header. If 0, 0.5 is used.
+	MinFrequency float64
+	// MaxHeaders is the maximum number of headers in the precompiled header, or 0 for no limit
+	MaxHeaders int
+	// LocalSystem is used for finding out which includes are system headers. If nil, only the standard
+	// headers are used.
+	LocalSystem *LocalSystem
+	// Filename is the name of the precompiled header, relative to the root path. If empty, DefaultPCHFilename is used.
+	Filename string
+	// Write is true if the precompiled header should be written. This only works for files on the OS file system.
+	Write bool
+}
+
+// PCHHeader is a header in a precompiled header
+type PCHHeader struct {
+	Name string `json:"name"`
+	// TranslationUnits is the number of translation units that include the header, directly or through project headers
+	TranslationUnits int `json:"translation_units"`
+}
+
+// PrecompiledHeader is a suggested precompiled header, with the system headers that most translation units include
+type PrecompiledHeader struct {
+	// Filename is relative to the root path
+	Filename string   `json:"filename"`
+	Language Language `json:"language"`
+	// Headers are ordered like the includes of a source file: C system headers, C++ standard headers
+	// and then third-party headers, sorted by name within each category
+	Headers []PCHHeader `json:"headers"`
+	// TranslationUnits is the number of C or C++ files that the precompiled header is for
+	TranslationUnits int `json:"translation_units"`
+}
+
+// PCHFlags are the compiler flags for building and for using a precompiled header
+type PCHFlags struct {
+	// Build are the arguments for building the precompiled header, to be used with the same compile flags
+	// as the translation units
+	Build []string `json:"build"`
+	// Use are the flags for compiling the translation units with the precompiled header
+	Use []string `json:"use"`
+}
+
+// GCCFlags returns the flags for GCC, which finds pch.hpp.gch when pch.hpp is included
+func (pch *PrecompiledHeader) GCCFlags() PCHFlags {
+	return pch.flags(".gch")
+}
+
+// ClangFlags returns the flags for Clang, which finds pch.hpp.pch when pch.hpp is included
+func (pch *PrecompiledHeader) ClangFlags() PCHFlags {
+	return pch.flags(".pch")
+}
+
+// flags returns the flags for a compiler that uses the given extension for precompiled headers
+func (pch *PrecompiledHeader) flags(ext string) PCHFlags {
+	language := "c++-header"
+	if pch.Language == LanguageC {
+		language = "c-header"
+	}
+	return PCHFlags{
+		Build: []string{"-x", language, pch.Filename, "-o", pch.Filename + ext},
+		Use:   []string{"-include", pch.Filename, "-Winvalid-pch"},
+	}
+}
+
+// Content returns the contents of the precompiled header
+func (pch *PrecompiledHeader) Content() string {
+	var sb strings.Builder
+	sb.WriteString("// Generated by autocpp\n#pragma once\n")
+	previous := IncludeCategory(-1)
+	for _, header := range pch.Headers {
+		include := Include{Name: header.Name, System: true}
+		if category := CategoryOf(include); category != previous {
+			sb.WriteByte('\n')
+			previous = category
+		}
+		sb.WriteString(include.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// SuggestPrecompiledHeader proposes a precompiled header with the system headers that are included by at least
+// opts.MinFrequency of the translation units, and by at least two of them. Project headers are never part of it,
+// since they change too often, and neither is assert.h, since it depends on NDEBUG. The precompiled header is for
+// C++ if the project has any C++ files, and else for C. The includes are counted from ShortIncludes, for each
+// translation unit, directly or through the project headers that it includes.
+func (src *Sources) SuggestPrecompiledHeader(opts PCHOptions) (*PrecompiledHeader, error) {
+	if opts.Write && !src.osFS {
+		return nil, errors.New("only files on the OS file system can be written")
+	}
+	minFrequency := opts.MinFrequency
+	if minFrequency == 0 {
+		minFrequency = 0.5
+	}
+	pch := &PrecompiledHeader{Filename: opts.Filename, Language: src.Languages()[0], Headers: []PCHHeader{}}
+	if pch.Filename == "" {
+		pch.Filename = DefaultPCHFilename
+	}
+	if opts.LocalSystem != nil {
+		src.FindIncludePaths(opts.LocalSystem)
+	}
+	translationUnits := src.absFilenamesCPP
+	if pch.Language == LanguageC {
+		translationUnits = src.absFilenamesC
+	}
+	pch.TranslationUnits = len(translationUnits)
+	graph := src.IncludeGraph()
+	counts := make(map[string]int)
+	for _, filename := range translationUnits {
+		for name := range src.reachableIncludes(graph, filename) {
+			counts[name]++
+		}
+	}
+	minCount := int(math.Ceil(minFrequency * float64(len(translationUnits))))
+	if minCount < 2 {
+		minCount = 2
+	}
+	for _, name := range src.ShortIncludes() {
+		if counts[name] >= minCount && src.isStableSystemHeader(name) {
+			pch.Headers = append(pch.Headers, PCHHeader{Name: name, TranslationUnits: counts[name]})
+		}
+	}
+	if opts.MaxHeaders > 0 && len(pch.Headers) > opts.MaxHeaders {
+		sort.SliceStable(pch.Headers, func(i, j int) bool {
+			return pch.Headers[i].TranslationUnits > pch.Headers[j].TranslationUnits
+		})
+		pch.Headers = pch.Headers[:opts.MaxHeaders]
+	}
+	sort.SliceStable(pch.Headers, func(i, j int) bool {
+		a, b := CategoryOf(Include{Name: pch.Headers[i].Name, System: true}), CategoryOf(Include{Name: pch.Headers[j].Name, System: true})
+		if a != b {
+			return a < b
+		}
+		return pch.Headers[i].Name < pch.Headers[j].Name
+	})
+	if opts.Write && len(pch.Headers) > 0 {
+		if err := writeFile(filepath.Join(src.rootPath, filepath.FromSlash(pch.Filename)), []byte(pch.Content())); err != nil {
+			return pch, err
+		}
+	}
+	return pch, nil
+}
+
+// reachableIncludes returns the include names of the given file and of the project headers that it includes,
+// directly or indirectly
+func (src *Sources) reachableIncludes(graph map[string][]string, filename string) map[string]bool {
+	names := make(map[string]bool)
+	visited := map[string]bool{filename: true}
+	queue := []string{filename}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, include := range src.FileIncludes(current) {
+			names[include.Name] = true
+		}
+		for _, next := range graph[current] {
+			if !visited[next] {
+				visited[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return names
+}
+
+// isStableSystemHeader checks if the include name is a standard header, or a header that was found outside
+// of the project by FindIncludePaths, and that it is not a project header or assert.h
+func (src *Sources) isStableSystemHeader(name string) bool {
+	if name == "assert.h" || name == "cassert" {
+		return false
+	}
+	for _, header := range src.absFilenamesHeader {
+		if src.fsPath(header) == name || strings.HasSuffix(header, string(filepath.Separator)+filepath.FromSlash(name)) {
+			return false
+		}
+	}
+	if isCSystemHeader(name) || isCXXStandardHeader(name) {
+		return true
+	}
+	found, ok := src.foundMap[name]
+	if !ok {
+		return false
+	}
+	rootPath, err := filepath.Abs(src.rootPath)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(rootPath, found)
+	return err == nil && (rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
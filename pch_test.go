@@ -0,0 +1,88 @@
+package autocpp
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSuggestPrecompiledHeader(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.cpp":    {Data: []byte("#include \"common.h\"\n#include <SDL2/SDL.h>\n#include <cassert>\n")},
+		"b.cpp":    {Data: []byte("#include <vector>\n#include <SDL2/SDL.h>\n#include <cassert>\n#include <stdio.h>\n")},
+		"c.cpp":    {Data: []byte("#include \"common.h\"\n#include <map>\n")},
+		"common.h": {Data: []byte("#pragma once\n#include <vector>\n#include <string>\n")},
+	}
+	src, err := NewSourcesWithOptions("project", SourcesOptions{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.SuggestPrecompiledHeader(PCHOptions{Write: true}); err == nil {
+		t.Errorf("expected an error when writing files that are not on the OS file system")
+	}
+	pch, err := src.SuggestPrecompiledHeader(PCHOptions{LocalSystem: newTestSystem(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []PCHHeader{{"string", 2}, {"vector", 3}, {"SDL2/SDL.h", 2}}
+	if !reflect.DeepEqual(pch.Headers, expected) || pch.Language != LanguageCXX || pch.TranslationUnits != 3 {
+		t.Errorf("unexpected precompiled header: %+v", pch)
+	}
+	if content := pch.Content(); content != "// Generated by autocpp\n#pragma once\n\n#include <string>\n#include <vector>\n\n#include <SDL2/SDL.h>\n" {
+		t.Errorf("unexpected contents:\n%s", content)
+	}
+	if flags := pch.GCCFlags(); !reflect.DeepEqual(flags, PCHFlags{
+		Build: []string{"-x", "c++-header", "pch.hpp", "-o", "pch.hpp.gch"},
+		Use:   []string{"-include", "pch.hpp", "-Winvalid-pch"},
+	}) {
+		t.Errorf("unexpected GCC flags: %+v", flags)
+	}
+	if flags := pch.ClangFlags(); flags.Build[len(flags.Build)-1] != "pch.hpp.pch" {
+		t.Errorf("unexpected Clang flags: %+v", flags)
+	}
+	// Without a LocalSystem, only the standard headers are known to be system headers
+	pch, err = src.SuggestPrecompiledHeader(PCHOptions{MaxHeaders: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pch.Headers, []PCHHeader{{"vector", 3}}) {
+		t.Errorf("unexpected headers: %+v", pch.Headers)
+	}
+	pch, err = src.SuggestPrecompiledHeader(PCHOptions{MinFrequency: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pch.Headers, []PCHHeader{{"vector", 3}}) {
+		t.Errorf("unexpected headers for a minimum frequency of 1: %+v", pch.Headers)
+	}
+}
+
+func TestSuggestPrecompiledHeaderWrite(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, "a.c", "b.c")
+	for _, name := range []string{"a.c", "b.c"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("#include <stdio.h>\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src, err := NewSources(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pch, err := src.SuggestPrecompiledHeader(PCHOptions{Filename: "pch.h", Write: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pch.Language != LanguageC || pch.GCCFlags().Build[1] != "c-header" {
+		t.Errorf("expected a precompiled header for C: %+v", pch)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "pch.h"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "// Generated by autocpp\n#pragma once\n\n#include <stdio.h>\n" {
+		t.Errorf("unexpected contents:\n%s", data)
+	}
+}
@@ -1,8 +1,44 @@
 package autocpp
 
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
 type PackageConfig struct {
+	sysroot string   // the value of PKG_CONFIG_SYSROOT_DIR, if set
+	libdirs []string // the value of PKG_CONFIG_LIBDIR, if set
 }
 
 func NewPackageConfig() *PackageConfig {
 	return &PackageConfig{}
 }
+
+// Environ returns the environment that pkg-config should run with. If a sysroot is used,
+// PKG_CONFIG_SYSROOT_DIR is set so that pkg-config prefixes -I and -L flags with the sysroot,
+// and PKG_CONFIG_LIBDIR is set so that only .pc files for the target system are found.
+func (pc *PackageConfig) Environ() []string {
+	environ := os.Environ()
+	if pc.sysroot != "" {
+		environ = append(environ, "PKG_CONFIG_SYSROOT_DIR="+pc.sysroot)
+	}
+	if len(pc.libdirs) > 0 {
+		environ = append(environ, "PKG_CONFIG_LIBDIR="+strings.Join(pc.libdirs, string(filepath.ListSeparator)))
+		// PKG_CONFIG_PATH would add directories from the host system
+		environ = append(environ, "PKG_CONFIG_PATH=")
+	}
+	return environ
+}
+
+// Run runs pkg-config with the given arguments, like "--cflags" and "sdl2", and returns the trimmed output
+func (pc *PackageConfig) Run(args ...string) (string, error) {
+	cmd := exec.Command("pkg-config", args...)
+	cmd.Env = pc.Environ()
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,163 @@
+package autocpp
+
+import (
+	"context"
+	"io/fs"
+	"sort"
+)
+
+// SourcesDiff describes what changed when Sources were refreshed
+type SourcesDiff struct {
+	AddedFiles   []string `json:"added_files,omitempty"`
+	RemovedFiles []string `json:"removed_files,omitempty"`
+	ChangedFiles []string `json:"changed_files,omitempty"`
+	// AddedIncludes and RemovedIncludes are the #include lines that were added to or removed from
+	// a file. Includes that only moved to a different line are not part of the diff.
+	AddedIncludes   []Include `json:"added_includes,omitempty"`
+	RemovedIncludes []Include `json:"removed_includes,omitempty"`
+	// AddedShortIncludes and RemovedShortIncludes are the short include names that are new to the
+	// project, or that are no longer included by any file
+	AddedShortIncludes   []string `json:"added_short_includes,omitempty"`
+	RemovedShortIncludes []string `json:"removed_short_includes,omitempty"`
+	// Found and NotFound are the results of finding the paths of AddedShortIncludes,
+	// if FindIncludePaths has been called before
+	Found    map[string]string `json:"found,omitempty"`
+	NotFound []string          `json:"not_found,omitempty"`
+}
+
+// Empty checks if nothing changed
+func (diff *SourcesDiff) Empty() bool {
+	return len(diff.AddedFiles) == 0 && len(diff.RemovedFiles) == 0 && len(diff.ChangedFiles) == 0
+}
+
+// Refresh walks the root path again and only reads the files that are new, or that have a different
+// size, modification time or contents than before. If FindIncludePaths has been called, only the
+// paths of the short include names that are new to the project are found.
+func (src *Sources) Refresh() (*SourcesDiff, error) {
+	return src.RefreshContext(context.Background())
+}
+
+// RefreshContext is like Refresh, but stops when the given context is canceled.
+// If a file can not be read, the sources are left as they were.
+func (src *Sources) RefreshContext(ctx context.Context) (*SourcesDiff, error) {
+	oldShortIncludes := src.ShortIncludes()
+	found, err := src.walk(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var diff SourcesDiff
+	oldFiles := src.files
+	headers, cFiles, cppFiles := src.sortFilenames(found)
+	allFilenames := append(append(append([]string{}, headers...), cFiles...), cppFiles...)
+	var toRead []string
+	for _, path := range allFilenames {
+		f, ok := oldFiles[path]
+		if !ok {
+			diff.AddedFiles = append(diff.AddedFiles, path)
+			toRead = append(toRead, path)
+			continue
+		}
+		info, err := fs.Stat(src.fsys, src.fsPath(path))
+		if err != nil || info.Size() != f.size || !info.ModTime().Equal(f.modTime) {
+			toRead = append(toRead, path)
+		}
+	}
+	readFiles, err := src.readFiles(ctx, toRead)
+	if err != nil {
+		return nil, err
+	}
+	newFiles := make(map[string]*sourceFile, len(found))
+	for _, path := range allFilenames {
+		if f, ok := oldFiles[path]; ok {
+			newFiles[path] = f
+		}
+	}
+	for _, f := range readFiles {
+		if oldFile, ok := oldFiles[f.name]; ok {
+			if oldFile.hash == f.hash {
+				// Only the modification time changed
+				oldFile.modTime = f.modTime
+				continue
+			}
+			diff.ChangedFiles = append(diff.ChangedFiles, f.name)
+			added, removed := diffIncludes(oldFile.includes, f.includes)
+			diff.AddedIncludes = append(diff.AddedIncludes, added...)
+			diff.RemovedIncludes = append(diff.RemovedIncludes, removed...)
+		} else {
+			diff.AddedIncludes = append(diff.AddedIncludes, f.includes...)
+		}
+		newFiles[f.name] = f
+	}
+	for path, f := range oldFiles {
+		if _, ok := newFiles[path]; !ok {
+			diff.RemovedFiles = append(diff.RemovedFiles, path)
+			diff.RemovedIncludes = append(diff.RemovedIncludes, f.includes...)
+		}
+	}
+	sort.Strings(diff.RemovedFiles)
+	src.absFilenamesHeader, src.absFilenamesC, src.absFilenamesCPP = headers, cFiles, cppFiles
+	src.files = newFiles
+	src.combine()
+
+	newShortIncludes := src.ShortIncludes()
+	diff.AddedShortIncludes = subtractS(newShortIncludes, oldShortIncludes)
+	diff.RemovedShortIncludes = subtractS(oldShortIncludes, newShortIncludes)
+	for _, include := range diff.RemovedShortIncludes {
+		delete(src.foundMap, include)
+		delete(src.resolutions, include)
+	}
+	if src.locsys != nil && len(diff.AddedShortIncludes) > 0 {
+		diff.Found = make(map[string]string)
+		search := src.newIncludeSearch(src.locsys)
+		for _, include := range diff.AddedShortIncludes {
+			if resolution := src.resolve(src.locsys, search, include); resolution.Found {
+				src.foundMap[include] = resolution.Path
+				diff.Found[include] = resolution.Path
+			} else {
+				diff.NotFound = append(diff.NotFound, include)
+			}
+		}
+	}
+	return &diff, nil
+}
+
+// diffIncludes compares the includes of two versions of a file, ignoring line numbers
+func diffIncludes(oldIncludes, newIncludes []Include) (added, removed []Include) {
+	key := func(include Include) string {
+		return include.String()
+	}
+	count := make(map[string]int)
+	for _, include := range oldIncludes {
+		count[key(include)]++
+	}
+	for _, include := range newIncludes {
+		if count[key(include)] > 0 {
+			count[key(include)]--
+		} else {
+			added = append(added, include)
+		}
+	}
+	count = make(map[string]int)
+	for _, include := range newIncludes {
+		count[key(include)]++
+	}
+	for _, include := range oldIncludes {
+		if count[key(include)] > 0 {
+			count[key(include)]--
+		} else {
+			removed = append(removed, include)
+		}
+	}
+	return added, removed
+}
+
+// subtractS returns the elements of xs that are not in ys
+func subtractS(xs, ys []string) []string {
+	var result []string
+	for _, x := range xs {
+		if !hasS(ys, x) {
+			result = append(result, x)
+		}
+	}
+	return result
+}
@@ -0,0 +1,139 @@
+package autocpp
+
+import (
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestParseIncludeLine(t *testing.T) {
+	tests := map[string]Include{
+		"#include <vector>":                  {Name: "vector", System: true},
+		"  #  include \"app.h\" // the app":  {Name: "app.h"},
+		"#include <SDL2/SDL.h>/* comment */": {Name: "SDL2/SDL.h", System: true},
+	}
+	for line, expected := range tests {
+		include, ok := parseIncludeLine(line)
+		if !ok || include != expected {
+			t.Errorf("%q: expected %v, got %v (%v)", line, expected, include, ok)
+		}
+	}
+	for _, line := range []string{"#include_next <stdlib.h>", "#define INCLUDE", "// #include <vector>", "#include", "#include <broken", "#include FT_FREETYPE_H", "#include INCLUDE(file)"} {
+		if include, ok := parseIncludeLine(line); ok {
+			t.Errorf("%q: did not expect an include, got %v", line, include)
+		}
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"main.cpp": {Data: []byte("#include <vector>\n#include \"app.h\"\n"), ModTime: modTime},
+		"app.h":    {Data: []byte("#include <string>\n"), ModTime: modTime},
+		"old.cpp":  {Data: []byte("#include <list>\n"), ModTime: modTime},
+	}
+	src, err := NewSourcesWithOptions("project", SourcesOptions{FS: fsys, LocalIncludeDirectories: []string{"."}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.FindIncludePaths(newTestSystem(t))
+
+	diff, err := src.Refresh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.Empty() {
+		t.Errorf("expected no changes, got %+v", diff)
+	}
+
+	later := modTime.Add(time.Minute)
+	fsys["main.cpp"] = &fstest.MapFile{Data: []byte("#include \"app.h\"\n#include <vector>\n#include <thread>\n"), ModTime: later}
+	fsys["app.h"] = &fstest.MapFile{Data: []byte("#include <string>\n"), ModTime: later} // touched, but not changed
+	fsys["new.c"] = &fstest.MapFile{Data: []byte("#include <stdio.h>\n#include <missing.h>\n"), ModTime: later}
+	delete(fsys, "old.cpp")
+
+	diff, err = src.Refresh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(diff.AddedFiles, []string{filepath.Join("project", "new.c")}) {
+		t.Errorf("unexpected added files: %v", diff.AddedFiles)
+	}
+	if !reflect.DeepEqual(diff.RemovedFiles, []string{filepath.Join("project", "old.cpp")}) {
+		t.Errorf("unexpected removed files: %v", diff.RemovedFiles)
+	}
+	if !reflect.DeepEqual(diff.ChangedFiles, []string{filepath.Join("project", "main.cpp")}) {
+		t.Errorf("unexpected changed files: %v", diff.ChangedFiles)
+	}
+	expectedAdded := []Include{
+		{File: filepath.Join("project", "new.c"), Line: 1, Name: "stdio.h", System: true},
+		{File: filepath.Join("project", "new.c"), Line: 2, Name: "missing.h", System: true},
+		{File: filepath.Join("project", "main.cpp"), Line: 3, Name: "thread", System: true},
+	}
+	if !reflect.DeepEqual(diff.AddedIncludes, expectedAdded) {
+		t.Errorf("expected added includes %v, got %v", expectedAdded, diff.AddedIncludes)
+	}
+	if !reflect.DeepEqual(diff.RemovedIncludes, []Include{{File: filepath.Join("project", "old.cpp"), Line: 1, Name: "list", System: true}}) {
+		t.Errorf("unexpected removed includes: %v", diff.RemovedIncludes)
+	}
+	if !reflect.DeepEqual(diff.AddedShortIncludes, []string{"missing.h", "stdio.h", "thread"}) {
+		t.Errorf("unexpected new short includes: %v", diff.AddedShortIncludes)
+	}
+	if !reflect.DeepEqual(diff.RemovedShortIncludes, []string{"list"}) {
+		t.Errorf("unexpected removed short includes: %v", diff.RemovedShortIncludes)
+	}
+	if diff.Found["stdio.h"] != "/usr/include/stdio.h" || diff.Found["thread"] != "/usr/include/c++/12/thread" {
+		t.Errorf("unexpected found includes: %v", diff.Found)
+	}
+	if !reflect.DeepEqual(diff.NotFound, []string{"missing.h"}) {
+		t.Errorf("unexpected includes that were not found: %v", diff.NotFound)
+	}
+	if _, ok := src.foundMap["list"]; ok {
+		t.Errorf("expected the removed include to be removed from the found includes")
+	}
+	if src.foundMap["app.h"] != filepath.Join("project", "app.h") {
+		t.Errorf("expected app.h to still be found, got %q", src.foundMap["app.h"])
+	}
+}
+
+// unreadableFS is a file system where one of the files can not be opened
+type unreadableFS struct {
+	fstest.MapFS
+	unreadable string
+}
+
+func (fsys unreadableFS) Open(name string) (fs.File, error) {
+	if name == fsys.unreadable {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return fsys.MapFS.Open(name)
+}
+
+func (fsys unreadableFS) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(struct{ fs.FS }{fsys}, name)
+}
+
+func TestRefreshReadError(t *testing.T) {
+	fsys := &unreadableFS{MapFS: fstest.MapFS{
+		"main.cpp": {Data: []byte("#include <vector>\n")},
+	}}
+	src, err := NewSourcesWithOptions("project", SourcesOptions{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys.MapFS["new.cpp"] = &fstest.MapFile{Data: []byte("#include <list>\n")}
+	fsys.MapFS["main.cpp"] = &fstest.MapFile{Data: []byte("#include <string>\n")}
+	fsys.unreadable = "new.cpp"
+	if _, err := src.Refresh(); err == nil {
+		t.Fatal("expected an error when a file can not be read")
+	}
+	if filenames := src.AllFilenames(); !reflect.DeepEqual(filenames, []string{filepath.Join("project", "main.cpp")}) {
+		t.Errorf("expected the filenames to be left as they were, got %v", filenames)
+	}
+	if shortIncludes := src.ShortIncludes(); !reflect.DeepEqual(shortIncludes, []string{"vector"}) {
+		t.Errorf("expected the includes to be left as they were, got %v", shortIncludes)
+	}
+}
@@ -0,0 +1,114 @@
+package autocpp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ReportSchemaVersion is increased whenever fields of a Report are renamed or removed,
+// or change meaning. Adding fields does not change the version. Version 2 changed the candidates
+// of a resolution from paths to Candidate records.
+const ReportSchemaVersion = 2
+
+// Report is the result of analyzing the sources on a LocalSystem, for tools that ingest JSON or YAML
+type Report struct {
+	SchemaVersion int    `json:"schema_version"`
+	Root          string `json:"root"`
+	// Languages are "c" and "c++". Standards maps each language to the oldest standard that has
+	// all the standard headers that are included, like "c++17".
+	Languages []string          `json:"languages"`
+	Standards map[string]string `json:"standards"`
+	// Files are the source files, with paths relative to Root, sorted by path
+	Files []ReportFile `json:"files"`
+	// Includes are the short include names, sorted
+	Includes []string `json:"includes"`
+	// Resolutions are how each short include name was found, sorted by include name
+	Resolutions []Resolution `json:"resolutions"`
+	Libraries   []string     `json:"libraries"`
+	LinkFlags   []string     `json:"link_flags"`
+	Warnings    []string     `json:"warnings"`
+}
+
+// ReportFile is a source file in a Report
+type ReportFile struct {
+	Path     string    `json:"path"`
+	Language string    `json:"language"` // "c", "c++" or "header"
+	Includes []Include `json:"includes"`
+}
+
+// Report finds the include paths of the sources and returns a report that can be written as JSON or YAML
+func (src *Sources) Report(locsys *LocalSystem) *Report {
+	src.FindIncludePaths(locsys)
+	report := &Report{
+		SchemaVersion: ReportSchemaVersion,
+		Root:          filepath.ToSlash(src.rootPath),
+		Standards:     make(map[string]string),
+		Files:         []ReportFile{},
+		Includes:      src.ShortIncludes(),
+		Resolutions:   src.Resolutions(),
+		Libraries:     src.Libraries(),
+		LinkFlags:     src.LinkFlags(),
+		Warnings:      []string{},
+	}
+	for _, lang := range src.Languages() {
+		report.Languages = append(report.Languages, lang.String())
+		report.Standards[lang.String()] = src.Standard(lang)
+	}
+	addFiles := func(filenames []string, language string) {
+		for _, filename := range filenames {
+			includes := []Include{}
+			for _, include := range src.FileIncludes(filename) {
+				include.File = src.fsPath(include.File)
+				includes = append(includes, include)
+			}
+			report.Files = append(report.Files, ReportFile{Path: src.fsPath(filename), Language: language, Includes: includes})
+		}
+	}
+	addFiles(src.absFilenamesHeader, "header")
+	addFiles(src.absFilenamesC, LanguageC.String())
+	addFiles(src.absFilenamesCPP, LanguageCXX.String())
+	sort.Slice(report.Files, func(i, j int) bool {
+		return report.Files[i].Path < report.Files[j].Path
+	})
+	if report.Includes == nil {
+		report.Includes = []string{}
+	}
+	if report.Libraries == nil {
+		report.Libraries = []string{}
+	}
+	if report.LinkFlags == nil {
+		report.LinkFlags = []string{}
+	}
+	for _, resolution := range report.Resolutions {
+		switch {
+		case !resolution.Found:
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s was not found", resolution.Include))
+		default:
+			report.Warnings = append(report.Warnings, resolution.Warnings...)
+		}
+	}
+	for _, cycle := range src.Cycles() {
+		var paths []string
+		for _, filename := range cycle {
+			paths = append(paths, src.fsPath(filename))
+		}
+		report.Warnings = append(report.Warnings, "include cycle: "+strings.Join(paths, ", "))
+	}
+	return report
+}
+
+// WriteJSON writes the report as indented JSON
+func (report *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// WriteYAML writes the report as YAML, with the same field names as the JSON
+func (report *Report) WriteYAML(w io.Writer) error {
+	return writeYAML(w, report)
+}
@@ -0,0 +1,152 @@
+package autocpp
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReport(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.cpp":  {Data: []byte("#include <vector>\n#include <optional>\n#include <SDL2/SDL.h>\n#include \"a.h\"\n")},
+		"a.h":       {Data: []byte("#include \"b.h\"\n#include <missing.h>\n")},
+		"b.h":       {Data: []byte("#include \"a.h\"\n")},
+		"tool/x.c":  {Data: []byte("#include <stdint.h>\n#include <math.h>\n")},
+		"README.md": {Data: []byte("# not a source file\n")},
+	}
+	src, err := NewSourcesWithOptions("project", SourcesOptions{FS: fsys, LocalIncludeDirectories: []string{"."}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := src.Report(newTestSystem(t))
+	if report.SchemaVersion != ReportSchemaVersion {
+		t.Errorf("unexpected schema version %d", report.SchemaVersion)
+	}
+	if !reflect.DeepEqual(report.Standards, map[string]string{"c++": "c++17", "c": "c99"}) {
+		t.Errorf("unexpected standards: %v", report.Standards)
+	}
+	var paths []string
+	for _, f := range report.Files {
+		paths = append(paths, f.Path+":"+f.Language)
+	}
+	if strings.Join(paths, " ") != "a.h:header b.h:header main.cpp:c++ tool/x.c:c" {
+		t.Errorf("unexpected files: %v", paths)
+	}
+	if !reflect.DeepEqual(report.Libraries, []string{"sdl2"}) || !reflect.DeepEqual(report.LinkFlags, []string{"-lm"}) {
+		t.Errorf("unexpected libraries %v or link flags %v", report.Libraries, report.LinkFlags)
+	}
+	vector, ok := src.Resolution("vector")
+	if !ok || vector.Path != "/usr/include/c++/12/vector" || len(vector.Candidates) != 2 {
+		t.Errorf("unexpected resolution of vector: %+v", vector)
+	}
+	expectedWarnings := []string{
+		"missing.h was not found",
+		"optional was not found",
+		"include cycle: a.h, b.h",
+	}
+	if !reflect.DeepEqual(report.Warnings, expectedWarnings) {
+		t.Errorf("expected warnings %q, got %q", expectedWarnings, report.Warnings)
+	}
+
+	var first, second bytes.Buffer
+	if err := report.WriteJSON(&first); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.Report(newTestSystem(t)).WriteJSON(&second); err != nil {
+		t.Fatal(err)
+	}
+	if first.String() != second.String() {
+		t.Errorf("expected the same JSON every time, got:\n%s\nand:\n%s", first.String(), second.String())
+	}
+	var decoded Report
+	if err := json.Unmarshal(first.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, report) {
+		t.Errorf("the JSON did not decode to the same report")
+	}
+
+	var yaml bytes.Buffer
+	if err := report.WriteYAML(&yaml); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"schema_version: 2\n",
+		"standards:\n  c: c99\n  c++: c++17\n",
+		"  - path: tool/x.c\n    language: c\n    includes:\n      - file: tool/x.c\n        line: 1\n        name: stdint.h\n        system: true\n",
+		"  - include: missing.h\n    found: false\n    reason: not found in any include directory\n",
+		"link_flags:\n  - \"-lm\"\n",
+	} {
+		if !strings.Contains(yaml.String(), s) {
+			t.Errorf("expected the YAML to contain %q, got:\n%s", s, yaml.String())
+		}
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	type inner struct {
+		Name  string `json:"name"`
+		Skip  string `json:"skip,omitempty"`
+		Count int    `json:"count"`
+	}
+	value := struct {
+		Strings []string         `json:"strings"`
+		Empty   []string         `json:"empty"`
+		Inner   []inner          `json:"inner"`
+		Map     map[string]int   `json:"map"`
+		Nested  [][]string       `json:"nested"`
+		Kind    WatchEventKind   `json:"kind"`
+		Pointer *inner           `json:"pointer"`
+		Hidden  string           `json:"-"`
+		Omitted map[string]int   `json:"omitted,omitempty"`
+		Any     interface{}      `json:"any"`
+		Events  []WatchEventKind `json:"events,omitempty"`
+	}{
+		Strings: []string{"plain", "with: colon", "true", "42", "0x10", "", " space", "-pthread", "# comment", "c++"},
+		Empty:   []string{},
+		Inner:   []inner{{Name: "a", Count: 1}, {Name: "b", Skip: "x"}},
+		Map:     map[string]int{"z": 1, "a": 2},
+		Nested:  [][]string{{"x"}, {}},
+		Kind:    EventCycleIntroduced,
+		Any:     "value",
+	}
+	expected := `strings:
+  - plain
+  - "with: colon"
+  - "true"
+  - "42"
+  - "0x10"
+  - ""
+  - " space"
+  - "-pthread"
+  - "# comment"
+  - c++
+empty: []
+inner:
+  - name: a
+    count: 1
+  - name: b
+    skip: x
+    count: 0
+map:
+  a: 2
+  z: 1
+nested:
+  -
+    - x
+  - []
+kind: cycle-introduced
+pointer: null
+any: value
+`
+	var buf bytes.Buffer
+	if err := writeYAML(&buf, value); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
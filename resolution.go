@@ -0,0 +1,183 @@
+package autocpp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The rules that a Resolution can be decided by, in addition to the rules of the RankingPolicy
+const (
+	RuleIncludingFileDirectory  = "including-file-directory"
+	RuleSystemIncludeDirectory  = "system-include-directory"
+	RuleProjectIncludeDirectory = "project-include-directory"
+	RuleLocalIncludeDirectory   = "local-include-directory"
+	RuleOnlyCandidate           = "only-candidate"
+	// RuleLastSorted is used when the ranking policy scores several candidates the same,
+	// and the candidate that sorts last, which usually has the highest version number, is chosen
+	RuleLastSorted = "last-sorted"
+)
+
+// Resolution describes how a short include name was found, or that it was not found
+type Resolution struct {
+	Include string `json:"include"`
+	Path    string `json:"path,omitempty"`
+	Found   bool   `json:"found"`
+	// Rule is the rule that decided the path, like "system-include-directory" or "shortest"
+	Rule string `json:"rule,omitempty"`
+	// Reason explains why Path was chosen, or why nothing was found
+	Reason string `json:"reason"`
+	// ScoreRules are the names of the rules of the RankingPolicy, in the same order as the scores
+	// of the candidates
+	ScoreRules []string `json:"score_rules,omitempty"`
+	// Candidates are the include files that end with the include name, sorted by path, if the include
+	// was not found in any include directory
+	Candidates []Candidate `json:"candidates,omitempty"`
+	// Warnings are about ambiguous choices, like when several candidates are just as good
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Candidate is an include file that was considered for an include
+type Candidate struct {
+	Path   string `json:"path"`
+	Scores []int  `json:"scores"`
+	Chosen bool   `json:"chosen,omitempty"`
+}
+
+// RankingPolicy chooses among the include files that end with an include name, like
+// /usr/include/c++/12/vector and /usr/include/c++/13/vector for "vector"
+type RankingPolicy interface {
+	// Rules returns the names of the rules of the policy, the most important rule first
+	Rules() []string
+	// Score returns a score for each rule for the given candidate. Higher scores are better, and
+	// the scores are compared rule by rule, so a later rule only matters if the earlier rules tie.
+	Score(include, candidate string) []int
+}
+
+// DefaultRankingPolicy prefers paths with "++" in them, like /usr/include/c++/12/vector,
+// and then the shortest path
+type DefaultRankingPolicy struct{}
+
+// Rules returns "prefer-keyword" and "shortest"
+func (DefaultRankingPolicy) Rules() []string {
+	return []string{"prefer-keyword", "shortest"}
+}
+
+// Score scores paths with "++" higher, and then shorter paths higher
+func (DefaultRankingPolicy) Score(include, candidate string) []int {
+	keyword := 0
+	if strings.Contains(candidate, "++") {
+		keyword = 1
+	}
+	return []int{keyword, -len(candidate)}
+}
+
+// preferPaths is a RankingPolicy that prefers paths that contain one of the given strings
+type preferPaths struct {
+	policy     RankingPolicy
+	substrings []string
+}
+
+// PreferPaths returns a RankingPolicy that prefers candidates that contain one of the given strings,
+// like "/c++/13/" for a specific GCC version or "/third_party/" for a vendored copy, with the
+// first string being the most preferred. Other candidates are ranked by the given policy.
+func PreferPaths(policy RankingPolicy, substrings ...string) RankingPolicy {
+	return preferPaths{policy: policy, substrings: substrings}
+}
+
+// Rules returns "prefer-path" followed by the rules of the underlying policy
+func (pp preferPaths) Rules() []string {
+	return append([]string{"prefer-path"}, pp.policy.Rules()...)
+}
+
+// Score scores candidates that contain an earlier string higher
+func (pp preferPaths) Score(include, candidate string) []int {
+	score := 0
+	for i, substring := range pp.substrings {
+		if strings.Contains(candidate, substring) {
+			score = len(pp.substrings) - i
+			break
+		}
+	}
+	return append([]int{score}, pp.policy.Score(include, candidate)...)
+}
+
+// compareScores returns the index of the first rule where a and b differ, and whether a is better,
+// or -1 if they are the same
+func compareScores(a, b []int) (int, bool) {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return i, a[i] > b[i]
+		}
+	}
+	return -1, false
+}
+
+// rank chooses among the given sorted candidates with the ranking policy of the LocalSystem
+func (locsys *LocalSystem) rank(include string, paths []string) Resolution {
+	policy := locsys.rankingPolicy
+	if policy == nil {
+		policy = DefaultRankingPolicy{}
+	}
+	resolution := Resolution{Include: include, Found: true, ScoreRules: policy.Rules()}
+	for _, path := range paths {
+		resolution.Candidates = append(resolution.Candidates, Candidate{Path: path, Scores: policy.Score(include, path)})
+	}
+	if len(paths) == 1 {
+		resolution.Candidates[0].Chosen = true
+		resolution.Path = paths[0]
+		resolution.Rule = RuleOnlyCandidate
+		resolution.Reason = "the only include file that ends with " + include
+		return resolution
+	}
+	// Going backwards, so that the candidate that sorts last wins ties
+	best := len(paths) - 1
+	for i := len(paths) - 2; i >= 0; i-- {
+		if _, better := compareScores(resolution.Candidates[i].Scores, resolution.Candidates[best].Scores); better {
+			best = i
+		}
+	}
+	resolution.Candidates[best].Chosen = true
+	resolution.Path = paths[best]
+	// The rule that won is the one that put the chosen candidate before the best of the others
+	decidingRule := -1
+	var ties []string
+	for i, candidate := range resolution.Candidates {
+		if i == best {
+			continue
+		}
+		ruleIndex, _ := compareScores(resolution.Candidates[best].Scores, candidate.Scores)
+		if ruleIndex == -1 {
+			ties = append(ties, candidate.Path)
+		} else if decidingRule == -1 || ruleIndex > decidingRule {
+			decidingRule = ruleIndex
+		}
+	}
+	switch {
+	case len(ties) > 0:
+		resolution.Rule = RuleLastSorted
+		resolution.Warnings = append(resolution.Warnings, fmt.Sprintf("%s is ambiguous, %s is just as good as %s", include, resolution.Path, strings.Join(ties, ", ")))
+	case decidingRule < len(resolution.ScoreRules):
+		resolution.Rule = resolution.ScoreRules[decidingRule]
+	}
+	resolution.Reason = fmt.Sprintf("chose 1 of %d include files that end with %s, by the rule %s", len(paths), include, resolution.Rule)
+	return resolution
+}
+
+// Resolution returns how the given short include name was resolved by FindIncludePaths
+func (src *Sources) Resolution(include string) (Resolution, bool) {
+	resolution, ok := src.resolutions[include]
+	return resolution, ok
+}
+
+// Resolutions returns how the short include names were resolved by FindIncludePaths, sorted by include name
+func (src *Sources) Resolutions() []Resolution {
+	resolutions := make([]Resolution, 0, len(src.resolutions))
+	for _, resolution := range src.resolutions {
+		resolutions = append(resolutions, resolution)
+	}
+	sort.Slice(resolutions, func(i, j int) bool {
+		return resolutions[i].Include < resolutions[j].Include
+	})
+	return resolutions
+}
@@ -0,0 +1,78 @@
+package autocpp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRankDefaultPolicy(t *testing.T) {
+	locsys := newTestSystem(t)
+	resolution := locsys.rank("vector", locsys.Candidates("vector"))
+	if resolution.Path != "/usr/include/c++/12/vector" || resolution.Rule != "shortest" {
+		t.Errorf("expected the shortest path to be chosen by the shortest rule, got %+v", resolution)
+	}
+	if !reflect.DeepEqual(resolution.ScoreRules, []string{"prefer-keyword", "shortest"}) {
+		t.Errorf("unexpected score rules: %v", resolution.ScoreRules)
+	}
+	expected := []Candidate{
+		{Path: "/usr/include/c++/12/vector", Scores: []int{1, -26}, Chosen: true},
+		{Path: "/usr/include/x86_64-linux-gnu/c++/12/vector", Scores: []int{1, -43}},
+	}
+	if !reflect.DeepEqual(resolution.Candidates, expected) {
+		t.Errorf("expected candidates %+v, got %+v", expected, resolution.Candidates)
+	}
+	if len(resolution.Warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", resolution.Warnings)
+	}
+}
+
+// The candidate that sorts first must also be considered, like for shortestButPreferKeyword before
+func TestRankFirstCandidate(t *testing.T) {
+	locsys := &LocalSystem{}
+	if resolution := locsys.rank("x.h", []string{"/opt/only/x.h"}); resolution.Path != "/opt/only/x.h" {
+		t.Errorf("expected the only candidate to be chosen, got %+v", resolution)
+	}
+	if resolution := locsys.rank("x.h", []string{"/opt/c++/x.h", "/opt/vendor/lib/x.h"}); resolution.Path != "/opt/c++/x.h" || !resolution.Candidates[0].Chosen {
+		t.Errorf("expected the first candidate to be chosen, got %+v", resolution)
+	}
+}
+
+func TestRankPolicies(t *testing.T) {
+	fsys := fstest.MapFS{
+		"opt/a/config.h":              {Data: []byte("\n")},
+		"opt/b/config.h":              {Data: []byte("\n")},
+		"opt/c++/config.h":            {Data: []byte("\n")},
+		"opt/vendor/lib/zz/config.h":  {Data: []byte("\n")},
+		"opt/include/c++/12/optional": {Data: []byte("\n")},
+		"opt/include/c++/13/optional": {Data: []byte("\n")},
+	}
+	newSystem := func(policy RankingPolicy) *LocalSystem {
+		locsys, err := NewLocalSystemWithOptions(LocalSystemOptions{FS: fsys, IgnoreEnvironment: true, SystemIncludeDirectories: []string{"/opt"}, RankingPolicy: policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return locsys
+	}
+
+	locsys := newSystem(nil)
+	if resolution := locsys.rank("config.h", locsys.Candidates("config.h")); resolution.Path != "/opt/c++/config.h" || resolution.Rule != "prefer-keyword" {
+		t.Errorf("expected the path with ++ to be chosen by the prefer-keyword rule, got %+v", resolution)
+	}
+	resolution := locsys.rank("optional", locsys.Candidates("optional"))
+	if resolution.Path != "/opt/include/c++/13/optional" || resolution.Rule != RuleLastSorted {
+		t.Errorf("expected the highest version to win a tie, got %+v", resolution)
+	}
+	if len(resolution.Warnings) != 1 || !strings.Contains(resolution.Warnings[0], "/opt/include/c++/12/optional") {
+		t.Errorf("expected a warning about the tie, got %v", resolution.Warnings)
+	}
+
+	locsys = newSystem(PreferPaths(DefaultRankingPolicy{}, "/vendor/", "/c++/12/"))
+	if resolution := locsys.rank("config.h", locsys.Candidates("config.h")); resolution.Path != "/opt/vendor/lib/zz/config.h" || resolution.Rule != "prefer-path" {
+		t.Errorf("expected the vendored copy to be chosen by the prefer-path rule, got %+v", resolution)
+	}
+	if path, _ := locsys.FindIncludeFile("optional"); path != "/opt/include/c++/12/optional" {
+		t.Errorf("expected the preferred GCC version, got %s", path)
+	}
+}
@@ -0,0 +1,134 @@
+package autocpp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CompileCommand compiles a translation unit, for checking that it compiles
+type CompileCommand interface {
+	// Compile compiles the given code as the given language, from the given directory and with the given flags.
+	// If the code does not compile, the output of the compiler is returned together with ErrCompile.
+	Compile(ctx context.Context, dir string, lang Language, code string, flags []string) (string, error)
+}
+
+// ErrCompile is returned by a CompileCommand when the code does not compile
+var ErrCompile = errors.New("the code does not compile")
+
+// ExecCompiler is a CompileCommand that runs a compiler like g++ or clang++, with the code on stdin
+// and with -fsyntax-only, so that nothing is written
+type ExecCompiler struct {
+	// Command is the compiler. If empty, "c++" is used.
+	Command string
+	// Args are added before the flags, like "-std=c++20"
+	Args []string
+}
+
+// Compile runs the compiler, and returns ErrCompile if it exits with an error
+func (compiler ExecCompiler) Compile(ctx context.Context, dir string, lang Language, code string, flags []string) (string, error) {
+	command := compiler.Command
+	if command == "" {
+		command = "c++"
+	}
+	language := "c++"
+	if lang == LanguageC {
+		language = "c"
+	}
+	args := append([]string{"-fsyntax-only", "-x", language}, compiler.Args...)
+	args = append(append(args, flags...), "-")
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(code)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			return output.String(), ErrCompile
+		}
+		return output.String(), fmt.Errorf("could not run %s: %w", command, err)
+	}
+	return output.String(), nil
+}
+
+// SelfContainedOptions can be used for configuring CheckSelfContainedHeaders
+type SelfContainedOptions struct {
+	// Compiler compiles the translation units. If nil, ExecCompiler{} is used.
+	Compiler CompileCommand
+	// LocalSystem is used for finding the build flags of the project, like for BuildFlags
+	LocalSystem *LocalSystem
+	// Flags are added after the build flags, like "-DDEBUG"
+	Flags []string
+}
+
+// HeaderCompileError is a project header that does not compile on its own
+type HeaderCompileError struct {
+	Header   string   `json:"header"`
+	Language Language `json:"language"`
+	// Output is the output of the compiler
+	Output string `json:"output"`
+}
+
+// CheckSelfContainedHeaders compiles a translation unit that only includes the header, for each project header,
+// with the build flags of the project. The headers that do not compile on their own are returned, ordered by name.
+// An error is returned if the compiler could not be run, or if the files are not on the OS file system.
+func (src *Sources) CheckSelfContainedHeaders(ctx context.Context, opts SelfContainedOptions) ([]HeaderCompileError, error) {
+	if !src.osFS {
+		return nil, errors.New("only files on the OS file system can be compiled")
+	}
+	compiler := opts.Compiler
+	if compiler == nil {
+		compiler = ExecCompiler{}
+	}
+	flags := append(src.BuildFlags(opts.LocalSystem).CompileFlags(), opts.Flags...)
+	var (
+		mut      sync.Mutex
+		wg       sync.WaitGroup
+		failures []HeaderCompileError
+		firstErr error
+	)
+	semaphore := make(chan struct{}, src.concurrency)
+	for _, header := range src.absFilenamesHeader {
+		if ctx.Err() != nil {
+			break
+		}
+		header := header
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			lang := src.fileLanguage(header)
+			code := Include{Name: src.fsPath(header)}.String() + "\n"
+			output, err := compiler.Compile(ctx, src.rootPath, lang, code, flags)
+			mut.Lock()
+			defer mut.Unlock()
+			switch {
+			case errors.Is(err, ErrCompile):
+				failures = append(failures, HeaderCompileError{Header: header, Language: lang, Output: output})
+			case err != nil && firstErr == nil:
+				firstErr = err
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].Header < failures[j].Header
+	})
+	return failures, nil
+}
@@ -0,0 +1,79 @@
+package autocpp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// fakeCompiler is a compiler script that fails for headers that mention size_t, and that writes its arguments to args.txt
+const fakeCompiler = `#!/bin/sh
+echo "$@" > args.txt
+header=$(sed -n 's/^#include "\(.*\)"$/\1/p')
+if grep -q size_t "$header"; then
+	echo "$header:2:1: error: unknown type name 'size_t'" >&2
+	exit 1
+fi
+`
+
+func TestCheckSelfContainedHeaders(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake compiler is a shell script")
+	}
+	root := t.TempDir()
+	writeTestFiles(t, root, "main.c", "include/util.h", "include/ok.h")
+	for name, contents := range map[string]string{
+		"main.c":         "#include \"util.h\"\n#include \"ok.h\"\n",
+		"include/util.h": "#pragma once\nsize_t count;\n",
+	} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	compiler := filepath.Join(t.TempDir(), "cc")
+	if err := os.WriteFile(compiler, []byte(fakeCompiler), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	src, err := NewSources(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.concurrency = 1 // the arguments of the last run are checked
+	failures, err := src.CheckSelfContainedHeaders(context.Background(), SelfContainedOptions{
+		Compiler:    ExecCompiler{Command: compiler},
+		LocalSystem: newTestSystem(t),
+		Flags:       []string{"-DTEST"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 1 || failures[0].Header != filepath.Join(root, "include", "util.h") || failures[0].Language != LanguageC ||
+		failures[0].Output != "include/util.h:2:1: error: unknown type name 'size_t'\n" {
+		t.Errorf("unexpected failures: %+v", failures)
+	}
+	args, err := os.ReadFile(filepath.Join(root, "args.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(args), "-fsyntax-only -x c -Iinclude -DTEST -") {
+		t.Errorf("unexpected compiler arguments: %s", args)
+	}
+
+	if _, err := src.CheckSelfContainedHeaders(context.Background(), SelfContainedOptions{
+		Compiler:    ExecCompiler{Command: filepath.Join(root, "no-such-compiler")},
+		LocalSystem: newTestSystem(t),
+	}); err == nil {
+		t.Errorf("expected an error for a compiler that does not exist")
+	}
+	src, err = NewSourcesWithOptions("project", SourcesOptions{FS: fstest.MapFS{"a.h": {Data: []byte("\n")}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.CheckSelfContainedHeaders(context.Background(), SelfContainedOptions{LocalSystem: newTestSystem(t)}); err == nil {
+		t.Errorf("expected an error for files that are not on the OS file system")
+	}
+}
@@ -1,58 +1,225 @@
 package autocpp
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"io/fs"
 	"math"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 )
 
 type Sources struct {
-	verbose            bool
-	rootPath           string
-	absFilenamesHeader []string
-	absFilenamesCPP    []string
-	absFilenamesC      []string
-	entireSource       []byte
-	foundMap           map[string]string // from a short include name to the full path, if the include was found
+	verbose                        bool
+	output                         io.Writer // verbose output is written here, nil if not verbose
+	fsys                           fs.FS
+	osFS                           bool // the sources are read from os.DirFS(rootPath), so they can be watched
+	rootPath                       string
+	include                        globs
+	exclude                        globs
+	localIncludeDirectories        []string // relative to the root path, searched before the detected and default ones
+	removedLocalIncludeDirectories []string // never searched
+	detect                         bool     // detect local include directories from where the included headers are
+	concurrency                    int
+	absFilenamesHeader             []string
+	absFilenamesCPP                []string
+	absFilenamesC                  []string
+	ignoreGitignore                bool
+	files                          map[string]*sourceFile // from filename to contents and include records
+	entireSource                   []byte
+	locsys                         *LocalSystem          // the LocalSystem that was last used for finding include paths
+	foundMap                       map[string]string     // from a short include name to the full path, if the include was found
+	resolutions                    map[string]Resolution // from a short include name to how it was found, or not found
+}
+
+// SourcesOptions can be used for configuring new Sources
+type SourcesOptions struct {
+	// FS is the file system that the sources are read from. If nil, os.DirFS(rootPath) is used, or the
+	// contents of the archive if the root path is an archive that OpenArchive can read, like
+	// "libfoo-1.0.tar.gz". The files are then named as if the archive was a directory, like
+	// "libfoo-1.0.tar.gz/libfoo-1.0/src/foo.c". If FS is given, the root path is only used for naming the files.
+	FS fs.FS
+	// Exclude contains glob patterns, like "build", "third_party/*" or "**/generated/*.h", that are
+	// matched against both the path relative to the root path and the base name of every file and directory
+	Exclude []string
+	// Include contains glob patterns for the files that should be scanned. If empty, all C and C++
+	// files are scanned. Excluded and ignored files are never scanned.
+	Include []string
+	// IgnoreGitignore can be set to also scan files that are ignored by .gitignore files.
+	// The .git directory is always skipped.
+	IgnoreGitignore bool
+	// LocalIncludeDirectories are directories relative to the root path, like "src/include",
+	// that are searched before the detected and the default local include directories
+	LocalIncludeDirectories []string
+	// RemoveLocalIncludeDirectories are directories relative to the root path, like "..", that are
+	// never searched, even if they are detected or are one of the default local include directories
+	RemoveLocalIncludeDirectories []string
+	// NoDetection can be set to not search the directories within the project that contain
+	// headers that the project includes, unless they are given as local include directories
+	NoDetection bool
+	// Concurrency is the maximum number of directories or files that are read at the same time.
+	// If zero, the number of CPUs is used.
+	Concurrency int
+	// Output is where verbose output is written. If nil, os.Stdout is used.
+	Output  io.Writer
+	Verbose bool
 }
 
 func NewSources(rootPath string, verbose bool) (*Sources, error) {
+	return NewSourcesWithOptions(rootPath, SourcesOptions{Verbose: verbose})
+}
+
+// NewSourcesWithOptions is like NewSources, but can be configured with SourcesOptions
+func NewSourcesWithOptions(rootPath string, opts SourcesOptions) (*Sources, error) {
+	return NewSourcesContext(context.Background(), rootPath, opts)
+}
+
+// NewSourcesContext is like NewSourcesWithOptions, but stops walking directories and reading files
+// when the given context is canceled. Directories are walked and files are read in parallel,
+// with up to opts.Concurrency goroutines, but the order of the found files is always the same.
+func NewSourcesContext(ctx context.Context, rootPath string, opts SourcesOptions) (*Sources, error) {
 	var src Sources
 	src.rootPath = rootPath
-	src.verbose = verbose
-	err := filepath.Walk(rootPath, func(path string, info fs.FileInfo, err error) error {
+	src.verbose = opts.Verbose
+	src.output = verboseOutput(opts.Verbose, opts.Output)
+	src.fsys = opts.FS
+	if src.fsys == nil && IsArchive(rootPath) {
+		fsys, err := OpenArchive(rootPath)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		src.fsys = fsys
+	}
+	if src.fsys == nil {
+		src.fsys = os.DirFS(rootPath)
+		src.osFS = true
+	}
+	src.include = compileGlobs(opts.Include)
+	src.exclude = compileGlobs(opts.Exclude)
+	src.AddLocalIncludeDirectories(opts.LocalIncludeDirectories...)
+	src.RemoveLocalIncludeDirectories(opts.RemoveLocalIncludeDirectories...)
+	src.detect = !opts.NoDetection
+	src.concurrency = opts.Concurrency
+	if src.concurrency <= 0 {
+		src.concurrency = runtime.NumCPU()
+	}
+	src.ignoreGitignore = opts.IgnoreGitignore
+	found, err := src.walk(ctx)
+	if err != nil {
+		return nil, err
+	}
+	src.setFilenames(found)
+	if err := src.ReadAllContext(ctx); err != nil {
+		return nil, err
+	}
+	src.foundMap = make(map[string]string)
+	return &src, nil
+}
+
+// walk returns the slash-separated paths of all files below the root path that are not excluded or ignored
+func (src *Sources) walk(ctx context.Context) ([]string, error) {
+	return src.walkAndVisit(ctx, nil)
+}
+
+// walkAndVisit is like walk, but also calls visitDir for every directory that is walked.
+// visitDir may be called from several goroutines at the same time.
+func (src *Sources) walkAndVisit(ctx context.Context, visitDir func(dir string)) ([]string, error) {
+	var gi gitignore
+	if !src.ignoreGitignore {
+		if data, err := fs.ReadFile(src.fsys, ".git/info/exclude"); err == nil {
+			gi.patterns = parseGitignore(".", data)
 		}
-		switch strings.ToLower(filepath.Ext(path)) {
-		case ".h", ".hpp", ".hh", ".h++":
-			if verbose {
-				fmt.Printf("added: %q\n", path)
+	}
+	return walkParallel(ctx, src.fsys, ".", gi, src.concurrency, walkVisitor[gitignore]{
+		enter: func(dir string, gi gitignore) gitignore {
+			if visitDir != nil {
+				visitDir(dir)
 			}
-			src.absFilenamesHeader = append(src.absFilenamesHeader, path)
-		case ".c":
-			if verbose {
-				fmt.Printf("added: %q\n", path)
+			if !src.ignoreGitignore {
+				// The patterns are copied, since sibling directories must not see each others patterns
+				gi.patterns = append([]gitignorePattern{}, gi.patterns...)
+				gi.read(src.fsys, dir)
 			}
-			src.absFilenamesC = append(src.absFilenamesC, path)
-		case ".cpp", ".cc", ".cxx", ".c++":
-			if verbose {
-				fmt.Printf("added: %q\n", path)
+			return gi
+		},
+		skipDir: func(p string, d fs.DirEntry, gi gitignore) bool {
+			return d.Name() == ".git" || src.exclude.match(p) || gi.ignored(p, true)
+		},
+		keepFile: func(p string, d fs.DirEntry, gi gitignore) bool {
+			if src.exclude.match(p) || gi.ignored(p, false) {
+				return false
 			}
-			src.absFilenamesCPP = append(src.absFilenamesCPP, path)
-		}
-		return nil
+			return len(src.include) == 0 || src.include.match(p)
+		},
 	})
+}
+
+// setFilenames sorts the given slash-separated paths into header, C and C++ files
+func (src *Sources) setFilenames(found []string) {
+	src.absFilenamesHeader, src.absFilenamesC, src.absFilenamesCPP = src.sortFilenames(found)
+}
+
+// fileKind is what kind of source file a file is, by its extension
+type fileKind int
+
+const (
+	otherFile fileKind = iota
+	headerFile
+	cFile
+	cppFile
+)
+
+// kindOf returns the kind of source file that the filename has the extension of. The extension is not
+// case sensitive, so both foo.c and foo.C are C files.
+func kindOf(filename string) fileKind {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".h", ".hpp", ".hh", ".h++":
+		return headerFile
+	case ".c":
+		return cFile
+	case ".cpp", ".cc", ".cxx", ".c++":
+		return cppFile
+	}
+	return otherFile
+}
+
+// sortFilenames returns the filenames of the given slash-separated paths, sorted into header, C and C++ files
+func (src *Sources) sortFilenames(found []string) (headers, cFiles, cppFiles []string) {
+	for _, p := range found {
+		path := src.name(p)
+		switch kindOf(path) {
+		case headerFile:
+			logf(src.output, "added: %q\n", path)
+			headers = append(headers, path)
+		case cFile:
+			logf(src.output, "added: %q\n", path)
+			cFiles = append(cFiles, path)
+		case cppFile:
+			logf(src.output, "added: %q\n", path)
+			cppFiles = append(cppFiles, path)
+		}
+	}
+	return headers, cFiles, cppFiles
+}
+
+// name returns the filename for the given slash-separated path within the file system of the sources
+func (src *Sources) name(p string) string {
+	return filepath.Join(src.rootPath, filepath.FromSlash(p))
+}
+
+// fsPath returns the slash-separated path within the file system of the sources, for the given filename
+func (src *Sources) fsPath(name string) string {
+	rel, err := filepath.Rel(src.rootPath, name)
 	if err != nil {
-		return nil, err
+		return filepath.ToSlash(name)
 	}
-	src.ReadAll()
-	src.foundMap = make(map[string]string)
-	return &src, nil
+	return filepath.ToSlash(rel)
 }
 
 func (src *Sources) AllFilenames() []string {
@@ -63,21 +230,115 @@ func (src *Sources) AllFilenames() []string {
 	return allFilenames
 }
 
+// HeaderFilenames returns the header files of the sources
+func (src *Sources) HeaderFilenames() []string {
+	return src.absFilenamesHeader
+}
+
+// CFilenames returns the C source files of the sources
+func (src *Sources) CFilenames() []string {
+	return src.absFilenamesC
+}
+
+// CPPFilenames returns the C++ source files of the sources
+func (src *Sources) CPPFilenames() []string {
+	return src.absFilenamesCPP
+}
+
+// RootPath returns the root path that the sources were found in
+func (src *Sources) RootPath() string {
+	return src.rootPath
+}
+
+// Languages returns the languages of the source files. Projects with only header files are assumed to be C++.
+func (src *Sources) Languages() []Language {
+	var langs []Language
+	if len(src.absFilenamesCPP) > 0 || len(src.absFilenamesC) == 0 {
+		langs = append(langs, LanguageCXX)
+	}
+	if len(src.absFilenamesC) > 0 {
+		langs = append(langs, LanguageC)
+	}
+	return langs
+}
+
+// ReadAll reads all source files, using up to src.concurrency goroutines
 func (src *Sources) ReadAll() error {
-	allFilenames := src.AllFilenames()
-	lenall := len(allFilenames)
-	for i, path := range allFilenames {
-		if src.verbose {
-			fmt.Printf("[%d/%d, %.2f%%] Reading %s...\n", i+1, lenall, math.Round((float64(i+1)*100.0)/float64(lenall)), path)
+	return src.ReadAllContext(context.Background())
+}
+
+// ReadAllContext is like ReadAll, but stops reading files when the given context is canceled.
+// The contents are always combined in the same order, regardless of which file was read first.
+func (src *Sources) ReadAllContext(ctx context.Context) error {
+	files, err := src.readFiles(ctx, src.AllFilenames())
+	if err != nil {
+		return err
+	}
+	src.files = make(map[string]*sourceFile, len(files))
+	for _, f := range files {
+		src.files[f.name] = f
+	}
+	src.combine()
+	return nil
+}
+
+// readFiles reads and parses the given files, using up to src.concurrency goroutines
+func (src *Sources) readFiles(ctx context.Context, filenames []string) ([]*sourceFile, error) {
+	lenall := len(filenames)
+	files := make([]*sourceFile, lenall)
+	errs := make([]error, lenall)
+	indices := make(chan int)
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+		n  int
+	)
+	for w := 0; w < src.concurrency && w < lenall; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				path := filenames[i]
+				files[i], errs[i] = src.readFile(path)
+				if src.output != nil {
+					mu.Lock()
+					n++
+					logf(src.output, "[%d/%d, %.2f%%] Reading %s...\n", n, lenall, math.Round((float64(n)*100.0)/float64(lenall)), path)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+SEND:
+	for i := range filenames {
+		select {
+		case indices <- i:
+		case <-ctx.Done():
+			break SEND
 		}
-		data, err := os.ReadFile(path)
+	}
+	close(indices)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, err := range errs {
 		if err != nil {
-			return err
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// combine sets src.entireSource to the contents of all files, in the order of src.AllFilenames()
+func (src *Sources) combine() {
+	src.entireSource = nil
+	for _, path := range src.AllFilenames() {
+		if f, ok := src.files[path]; ok {
+			src.entireSource = append(src.entireSource, []byte("\n")...)
+			src.entireSource = append(src.entireSource, f.data...)
 		}
-		src.entireSource = append(src.entireSource, []byte("\n")...)
-		src.entireSource = append(src.entireSource, data...)
 	}
-	return nil
 }
 
 func (src *Sources) String() string {
@@ -117,27 +378,17 @@ func hasS(xs []string, e string) bool {
 // but without the surrounding "#include <...>" or "#include \"...\"".
 func (src *Sources) ShortIncludes() []string {
 	var includes []string
-	for _, includeLine := range src.IncludeLines() {
-		include := strings.TrimPrefix(includeLine, "#include")
-		include = strings.TrimSpace(include)
-		include = strings.TrimPrefix(include, "\"")
-		include = strings.TrimPrefix(include, "<")
-		include = strings.TrimSuffix(include, "\"")
-		include = strings.TrimSuffix(include, ">")
-		if !hasS(includes, include) {
-			includes = append(includes, include)
+	seen := make(map[string]bool)
+	for _, include := range src.Includes() {
+		if !seen[include.Name] {
+			seen[include.Name] = true
+			includes = append(includes, include.Name)
 		}
 	}
 	sort.Strings(includes)
 	return includes
 }
 
-// exists checks if the given path exists
-func exists(path string) bool {
-	_, err := os.Stat(path)
-	return err == nil
-}
-
 func shortest(xs []string) string {
 	minlen := -1
 	s := ""
@@ -153,87 +404,123 @@ func shortest(xs []string) string {
 	return s
 }
 
-// shotestButPreferKeyword tries to find the shortest string in the given slice,
-// but if a candidate has the given keyword, prefer that over shorter strings.
-// xs is expected to be sorted with sort.Strings first.
-func shortestButPreferKeyword(xs []string, keyword string) string {
-	minlen := -1
-	s := ""
-	hasKeyword := false
-	foundKeywordAlready := false
-	// loop in reverse order, assume xs is sorted and has the lowest version numbers first
-	for i := len(xs) - 1; i > 0; i-- {
-		x := xs[i]
-		hasKeyword = strings.Contains(x, keyword)
-		foundKeywordAlready = strings.Contains(s, keyword)
-		if (minlen == -1) || !foundKeywordAlready || (hasKeyword && len(x) < minlen) {
-			minlen = len(x)
-			s = x
-		}
-	}
-	if minlen == -1 {
-		return "" // none were shortest, no elements
-	}
-	return s
-}
-
 // FindIncludePaths fills src.foundMap with short include names and their corresponding paths.
 // It will also return a slice of the short include names that were not found.
 func (src *Sources) FindIncludePaths(locsys *LocalSystem) []string {
+	src.locsys = locsys
+	src.resolutions = make(map[string]Resolution)
 	var notFound []string
-OUT:
+	search := src.newIncludeSearch(locsys)
 	for _, include := range src.ShortIncludes() {
-		// First search system directories
-		for _, includeDirectory := range locsys.systemIncludeDirectories {
-			path := filepath.Join(includeDirectory, include)
-			if hasS(locsys.includeFiles, path) {
-				src.foundMap[include] = path
-				continue OUT
-			}
-		}
-		// Then search local directories
-		for _, includeDirectory := range locsys.localIncludeDirectories {
-			path := filepath.Join(includeDirectory, include)
-			if exists(path) {
-				src.foundMap[include] = path
-				continue OUT
-			}
-		}
-		// Then look for candidates in the include files that has been found on the system
-		var candidates []string
-		for _, includeFile := range locsys.includeFiles {
-			if strings.HasSuffix(includeFile, "/"+include) {
-				candidates = append(candidates, includeFile)
-			}
-		}
-		if len(candidates) == 0 {
+		if resolution := src.resolve(locsys, search, include); resolution.Found {
+			src.foundMap[include] = resolution.Path
+		} else {
+			delete(src.foundMap, include)
 			notFound = append(notFound, include)
-			continue
-		}
-		sort.Strings(candidates)
-		// candidates are now sorted
-		if src.verbose {
-			fmt.Printf("Candidates for %s:\n", include)
-			for _, candidate := range candidates {
-				fmt.Printf("\t%s\n", candidate)
-			}
 		}
-		path := shortestButPreferKeyword(candidates, "++")
-		if src.verbose {
-			fmt.Printf("\tChose:\n\t%s\n", path)
-		}
-		src.foundMap[include] = path
 	}
 	return notFound
 }
 
+// searchPath returns the system include directories for all languages of the sources
+func (src *Sources) searchPath(locsys *LocalSystem) []string {
+	var searchPath []string
+	for _, lang := range src.Languages() {
+		searchPath = appendUnique(searchPath, locsys.SearchPath(lang)...)
+	}
+	return searchPath
+}
+
+// includeSearch is what findIncludePath needs for every include name, found once per FindIncludePaths call
+type includeSearch struct {
+	searchPath []string                       // the system include directories
+	localDirs  []string                       // the local include directories, relative to the root path
+	nextTo     map[string]nextToIncludingFile // the headers that are next to the files that include them
+}
+
+// newIncludeSearch finds the directories and files that are searched for the includes of the sources
+func (src *Sources) newIncludeSearch(locsys *LocalSystem) includeSearch {
+	return includeSearch{
+		searchPath: src.searchPath(locsys),
+		localDirs:  src.localIncludeDirectoriesFor(locsys),
+		nextTo:     src.findNextToIncludingFiles(),
+	}
+}
+
+// resolve finds the path of a single short include name and remembers how it was found
+func (src *Sources) resolve(locsys *LocalSystem, search includeSearch, include string) Resolution {
+	resolution := src.findIncludePath(locsys, search, include)
+	if src.resolutions == nil {
+		src.resolutions = make(map[string]Resolution)
+	}
+	src.resolutions[include] = resolution
+	return resolution
+}
+
+// findIncludePath finds the path of a single short include name
+func (src *Sources) findIncludePath(locsys *LocalSystem, search includeSearch, include string) Resolution {
+	resolution := Resolution{Include: include}
+	found := func(path, rule, reason string) Resolution {
+		resolution.Path, resolution.Found, resolution.Rule, resolution.Reason = path, true, rule, reason
+		return resolution
+	}
+	// Like compilers, first search next to the files that include it with "..."
+	if next, ok := search.nextTo[include]; ok {
+		return found(next.path, RuleIncludingFileDirectory, "found next to "+next.includingFile)
+	}
+	// Then search system directories
+	if path, ok := src.findInSearchPath(locsys, search.searchPath, include); ok {
+		return found(path, RuleSystemIncludeDirectory, "found in the system include directory "+filepath.Dir(strings.TrimSuffix(path, filepath.FromSlash(include))))
+	}
+	// Then search the local include directories, relative to the root path
+	for _, includeDirectory := range search.localDirs {
+		if name, ok := src.localFile(path.Join(includeDirectory, include)); ok {
+			return found(name, RuleProjectIncludeDirectory, "found in the local include directory "+includeDirectory)
+		}
+	}
+	// Then search local directories that were given as absolute paths
+	for _, includeDirectory := range locsys.localIncludeDirectories {
+		path := filepath.Join(includeDirectory, include)
+		if filepath.IsAbs(path) && locsys.Exists(path) {
+			return found(path, RuleLocalIncludeDirectory, "found in the local include directory "+includeDirectory)
+		}
+	}
+	// Then look for candidates in the include files that has been found on the system
+	candidates := locsys.Candidates(include)
+	if len(candidates) == 0 {
+		resolution.Reason = "not found in any include directory"
+		return resolution
+	}
+	// candidates are now sorted
+	logf(src.output, "Candidates for %s:\n", include)
+	for _, candidate := range candidates {
+		logf(src.output, "\t%s\n", candidate)
+	}
+	resolution = locsys.rank(include, candidates)
+	logf(src.output, "\tChose:\n\t%s\n", resolution.Path)
+	return resolution
+}
+
+// IncludePath returns the path that was found for the given short include name by FindIncludePaths
+func (src *Sources) IncludePath(include string) (string, bool) {
+	path, ok := src.foundMap[include]
+	return path, ok
+}
+
 func (src *Sources) FindAndPrintIncludePaths(locsys *LocalSystem) {
+	src.FindAndWriteIncludePaths(os.Stdout, locsys)
+}
+
+// FindAndWriteIncludePaths is like FindAndPrintIncludePaths, but writes to the given io.Writer
+func (src *Sources) FindAndWriteIncludePaths(w io.Writer, locsys *LocalSystem) {
 	notFound := src.FindIncludePaths(locsys)
-	for _, path := range src.foundMap {
-		fmt.Printf("FOUND: %s\n", path)
+	for _, include := range src.ShortIncludes() {
+		if path, ok := src.foundMap[include]; ok {
+			fmt.Fprintf(w, "FOUND: %s\n", path)
+		}
 	}
 	for _, include := range notFound {
-		fmt.Printf("NOT FOUND: %s\n", include)
+		fmt.Fprintf(w, "NOT FOUND: %s\n", include)
 	}
 }
 
@@ -1,17 +1,23 @@
 package autocpp
 
 import (
+	"bytes"
 	"fmt"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
-
-	"github.com/xyproto/env"
+	"testing/fstest"
+	"time"
 )
 
-const exampleProjectDirectory = "~/cppprojects/fireworks"
+const exampleProjectDirectory = "testdata/fireworks"
 
-// an alternative to an "init" function, for initializing a new LocalSystem
+// an alternative to an "init" function, for initializing new Sources
 var src = func() *Sources {
-	src, err := NewSources(env.ExpandUser(exampleProjectDirectory), true)
+	src, err := NewSources(exampleProjectDirectory, true)
 	if err != nil {
 		panic(err)
 	}
@@ -26,3 +32,120 @@ func TestIncludes(t *testing.T) {
 func TestPrintIncludeInfo(t *testing.T) {
 	src.FindAndPrintIncludePaths(locsys)
 }
+
+func TestSourcesOptions(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.cpp":             {Data: []byte("#include <vector>\n#include \"app.h\"\n")},
+		"app.h":                {Data: []byte("#include <string>\n")},
+		"build/generated.cpp":  {Data: []byte("#include <generated.h>\n")},
+		"third_party/lib/x.h":  {Data: []byte("#include <x11.h>\n")},
+		"tools/old/legacy.cpp": {Data: []byte("#include <legacy.h>\n")},
+	}
+	var buf bytes.Buffer
+	src, err := NewSourcesWithOptions("project", SourcesOptions{
+		FS:          fsys,
+		Exclude:     []string{"build", "third_party/*", "legacy.cpp"},
+		Concurrency: 2,
+		Output:      &buf,
+		Verbose:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shortIncludes := src.ShortIncludes(); !reflect.DeepEqual(shortIncludes, []string{"app.h", "string", "vector"}) {
+		t.Errorf("unexpected includes: %v", shortIncludes)
+	}
+	if !strings.Contains(buf.String(), "added: \"project/main.cpp\"") {
+		t.Errorf("expected the verbose output to be written to the given writer, got %q", buf.String())
+	}
+}
+
+// concurrencyCountingFS records the highest number of directories that are read at the same time
+type concurrencyCountingFS struct {
+	fstest.MapFS
+	inFlight atomic.Int32
+	max      atomic.Int32
+}
+
+func (fsys *concurrencyCountingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	n := fsys.inFlight.Add(1)
+	defer fsys.inFlight.Add(-1)
+	for {
+		highest := fsys.max.Load()
+		if n <= highest || fsys.max.CompareAndSwap(highest, n) {
+			break
+		}
+	}
+	// Give the other workers a chance to read directories at the same time
+	time.Sleep(time.Millisecond)
+	return fsys.MapFS.ReadDir(name)
+}
+
+func TestWalkConcurrency(t *testing.T) {
+	fsys := &concurrencyCountingFS{MapFS: fstest.MapFS{}}
+	for i := 0; i < 100; i++ {
+		fsys.MapFS[fmt.Sprintf("module%d/file.cpp", i)] = &fstest.MapFile{Data: []byte("#include <vector>\n")}
+	}
+	const concurrency = 2
+	src, err := NewSourcesWithOptions("project", SourcesOptions{FS: fsys, Concurrency: concurrency})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(src.AllFilenames()); n != 100 {
+		t.Errorf("expected 100 files, got %d", n)
+	}
+	if highest := fsys.max.Load(); highest > concurrency {
+		t.Errorf("expected at most %d directories to be read at the same time, got %d", concurrency, highest)
+	}
+}
+
+func TestFixtureIncludePaths(t *testing.T) {
+	src, err := NewSourcesWithOptions(exampleProjectDirectory, SourcesOptions{Exclude: []string{"build"}, LocalIncludeDirectories: []string{".", "include"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedIncludes := []string{"SDL2/SDL.h", "cmath", "cstdint", "fireworks.h", "iostream", "particle.h", "thread", "vector"}
+	if shortIncludes := src.ShortIncludes(); !reflect.DeepEqual(shortIncludes, expectedIncludes) {
+		t.Errorf("expected %v, got %v", expectedIncludes, shortIncludes)
+	}
+	notFound := src.FindIncludePaths(newTestSystem(t))
+	if len(notFound) != 0 {
+		t.Errorf("expected all includes to be found, but these were not: %v", notFound)
+	}
+	expectedPaths := map[string]string{
+		"SDL2/SDL.h":  "/usr/include/SDL2/SDL.h",
+		"iostream":    "/usr/include/c++/12/iostream",
+		"vector":      "/usr/include/c++/12/vector",
+		"fireworks.h": filepath.Join(exampleProjectDirectory, "fireworks.h"),
+		"particle.h":  filepath.Join(exampleProjectDirectory, "include", "particle.h"),
+	}
+	for include, expected := range expectedPaths {
+		if src.foundMap[include] != expected {
+			t.Errorf("expected %s to be found at %s, got %q", include, expected, src.foundMap[include])
+		}
+	}
+	if !src.Thread() {
+		t.Errorf("expected <thread> to require threading flags")
+	}
+}
+
+func TestFixtureMapFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"src/main.c":     {Data: []byte("#include <stdio.h>\n#include \"util.h\"\n")},
+		"src/util.h":     {Data: []byte("#include <stdint.h>\n")},
+		"doc/README.txt": {Data: []byte("#include <not_a_source_file.h>\n")},
+	}
+	src, err := NewSourcesWithOptions("embedded", SourcesOptions{FS: fsys, LocalIncludeDirectories: []string{"src"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if langs := src.Languages(); !reflect.DeepEqual(langs, []Language{LanguageC}) {
+		t.Errorf("expected only C, got %v", langs)
+	}
+	if notFound := src.FindIncludePaths(newTestSystem(t)); len(notFound) != 0 {
+		t.Errorf("expected all includes to be found, but these were not: %v", notFound)
+	}
+	if src.foundMap["util.h"] != filepath.Join("embedded", "src", "util.h") {
+		t.Errorf("unexpected path for util.h: %q", src.foundMap["util.h"])
+	}
+}
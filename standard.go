@@ -0,0 +1,203 @@
+package autocpp
+
+import (
+	"strings"
+	"sync"
+)
+
+// cxxStandardHeaders maps standard C++ headers to the first C++ standard that has them
+var cxxStandardHeaders = map[string]string{
+	"array": "c++11", "atomic": "c++11", "chrono": "c++11", "codecvt": "c++11", "condition_variable": "c++11",
+	"cstdint": "c++11", "cinttypes": "c++11", "forward_list": "c++11", "future": "c++11", "initializer_list": "c++11",
+	"mutex": "c++11", "random": "c++11", "ratio": "c++11", "regex": "c++11", "scoped_allocator": "c++11",
+	"system_error": "c++11", "thread": "c++11", "tuple": "c++11", "type_traits": "c++11", "typeindex": "c++11",
+	"unordered_map": "c++11", "unordered_set": "c++11", "cfenv": "c++11", "cuchar": "c++11",
+	"shared_mutex": "c++14",
+	"any":          "c++17", "charconv": "c++17", "execution": "c++17", "filesystem": "c++17", "memory_resource": "c++17",
+	"optional": "c++17", "string_view": "c++17", "variant": "c++17",
+	"barrier": "c++20", "bit": "c++20", "compare": "c++20", "concepts": "c++20", "coroutine": "c++20",
+	"format": "c++20", "latch": "c++20", "numbers": "c++20", "ranges": "c++20", "semaphore": "c++20",
+	"source_location": "c++20", "span": "c++20", "stop_token": "c++20", "syncstream": "c++20", "version": "c++20",
+	"expected": "c++23", "flat_map": "c++23", "flat_set": "c++23", "generator": "c++23", "mdspan": "c++23",
+	"print": "c++23", "spanstream": "c++23", "stacktrace": "c++23", "stdfloat": "c++23",
+}
+
+// cStandardHeaders maps standard C headers to the first C standard that has them
+var cStandardHeaders = map[string]string{
+	"complex.h": "c99", "fenv.h": "c99", "inttypes.h": "c99", "stdbool.h": "c99", "stdint.h": "c99", "tgmath.h": "c99",
+	"stdalign.h": "c11", "stdatomic.h": "c11", "stdnoreturn.h": "c11", "threads.h": "c11", "uchar.h": "c11",
+	"stdbit.h": "c23", "stdckdint.h": "c23",
+}
+
+// cxxStandards and cStandards are the standards in order
+var (
+	cxxStandards = []string{"c++98", "c++11", "c++14", "c++17", "c++20", "c++23"}
+	cStandards   = []string{"c89", "c99", "c11", "c17", "c23"}
+)
+
+// Standard returns the oldest language standard, like "c++17" or "c11", that has all the standard
+// headers that the sources include for the given language. The sources may still need a newer
+// standard for language features that are not tied to a header.
+func (src *Sources) Standard(lang Language) string {
+	headers, standards := cxxStandardHeaders, cxxStandards
+	if lang == LanguageC {
+		headers, standards = cStandardHeaders, cStandards
+	}
+	index := 0
+	for _, include := range src.ShortIncludes() {
+		standard, ok := headers[include]
+		if !ok {
+			continue
+		}
+		for i, s := range standards {
+			if s == standard && i > index {
+				index = i
+			}
+		}
+	}
+	return standards[index]
+}
+
+// cxxHeadersForC maps C standard headers to the C++ headers that declare the same symbols in namespace std
+var cxxHeadersForC = map[string]string{
+	"assert.h": "cassert", "ctype.h": "cctype", "errno.h": "cerrno", "fenv.h": "cfenv", "float.h": "cfloat",
+	"inttypes.h": "cinttypes", "limits.h": "climits", "locale.h": "clocale", "math.h": "cmath",
+	"setjmp.h": "csetjmp", "signal.h": "csignal", "stdarg.h": "cstdarg", "stddef.h": "cstddef",
+	"stdint.h": "cstdint", "stdio.h": "cstdio", "stdlib.h": "cstdlib", "string.h": "cstring", "time.h": "ctime",
+	"uchar.h": "cuchar", "wchar.h": "cwchar", "wctype.h": "cwctype",
+}
+
+// standardHeaderSymbols lists well-known symbols of the standard headers. A symbol that is declared by
+// several headers, like size_t, is listed for each of them, and the first header is the canonical one.
+// C symbols are also found in namespace std, in the matching C++ header from cxxHeadersForC.
+var standardHeaderSymbols = []struct {
+	header  string
+	symbols []string
+}{
+	// C
+	{"stddef.h", []string{"size_t", "ptrdiff_t", "NULL", "offsetof", "max_align_t"}},
+	{"stdio.h", []string{"printf", "fprintf", "sprintf", "snprintf", "vprintf", "vfprintf", "vsnprintf", "puts", "fputs", "fgets", "fputc", "fgetc", "getchar", "putchar", "fopen", "fclose", "fread", "fwrite", "fseek", "ftell", "rewind", "fflush", "fscanf", "scanf", "sscanf", "perror", "remove", "rename", "tmpfile", "FILE", "EOF", "BUFSIZ", "stdin", "stdout", "stderr", "SEEK_SET", "SEEK_CUR", "SEEK_END", "size_t", "NULL"}},
+	{"stdlib.h", []string{"malloc", "calloc", "realloc", "free", "exit", "abort", "atexit", "atoi", "atol", "atof", "strtol", "strtoul", "strtoll", "strtoull", "strtod", "strtof", "getenv", "system", "qsort", "bsearch", "rand", "srand", "EXIT_SUCCESS", "EXIT_FAILURE", "RAND_MAX", "size_t", "NULL"}},
+	{"string.h", []string{"strlen", "strcpy", "strncpy", "strcat", "strncat", "strcmp", "strncmp", "strchr", "strrchr", "strstr", "strtok", "strspn", "strcspn", "strerror", "memcpy", "memmove", "memset", "memcmp", "memchr", "size_t", "NULL"}},
+	{"math.h", []string{"sqrt", "pow", "sin", "cos", "tan", "asin", "acos", "atan", "atan2", "sinh", "cosh", "tanh", "exp", "log", "log2", "log10", "floor", "ceil", "fabs", "fmod", "round", "trunc", "hypot", "cbrt", "fmin", "fmax", "isnan", "isinf", "HUGE_VAL", "INFINITY", "NAN", "M_PI"}},
+	{"stdint.h", []string{"int8_t", "int16_t", "int32_t", "int64_t", "uint8_t", "uint16_t", "uint32_t", "uint64_t", "intptr_t", "uintptr_t", "intmax_t", "uintmax_t", "INT8_MAX", "INT16_MAX", "INT32_MAX", "INT64_MAX", "INT32_MIN", "INT64_MIN", "UINT8_MAX", "UINT16_MAX", "UINT32_MAX", "UINT64_MAX", "SIZE_MAX"}},
+	{"inttypes.h", []string{"PRId8", "PRId16", "PRId32", "PRId64", "PRIu8", "PRIu16", "PRIu32", "PRIu64", "PRIx32", "PRIx64", "SCNd32", "SCNd64", "strtoimax", "strtoumax"}},
+	{"assert.h", []string{"assert"}},
+	{"ctype.h", []string{"isalpha", "isdigit", "isalnum", "isspace", "isupper", "islower", "ispunct", "isprint", "isxdigit", "toupper", "tolower"}},
+	{"time.h", []string{"time", "clock", "difftime", "mktime", "strftime", "localtime", "gmtime", "time_t", "clock_t", "CLOCKS_PER_SEC", "size_t", "NULL"}},
+	{"errno.h", []string{"errno", "EINVAL", "ENOENT", "ERANGE", "EDOM", "EAGAIN", "EINTR", "ENOMEM", "EEXIST"}},
+	{"limits.h", []string{"CHAR_BIT", "CHAR_MAX", "CHAR_MIN", "INT_MAX", "INT_MIN", "UINT_MAX", "LONG_MAX", "LONG_MIN", "ULONG_MAX", "LLONG_MAX", "LLONG_MIN", "ULLONG_MAX", "SHRT_MAX", "SHRT_MIN"}},
+	{"float.h", []string{"FLT_MAX", "FLT_MIN", "FLT_EPSILON", "DBL_MAX", "DBL_MIN", "DBL_EPSILON", "LDBL_MAX"}},
+	{"stdarg.h", []string{"va_list", "va_start", "va_end", "va_arg", "va_copy"}},
+	{"signal.h", []string{"signal", "raise", "sig_atomic_t", "SIGINT", "SIGTERM", "SIGSEGV", "SIGABRT", "SIG_IGN", "SIG_DFL"}},
+	{"setjmp.h", []string{"setjmp", "longjmp", "jmp_buf"}},
+	{"locale.h", []string{"setlocale", "localeconv", "LC_ALL", "LC_NUMERIC", "LC_CTYPE", "NULL"}},
+	{"wchar.h", []string{"wcslen", "wcscpy", "wcscmp", "wprintf", "fwprintf", "swprintf", "wint_t", "WEOF", "size_t", "NULL"}},
+	{"wctype.h", []string{"iswalpha", "iswdigit", "iswspace", "towupper", "towlower"}},
+	// C++
+	{"cstddef", []string{"std::byte", "std::nullptr_t", "std::max_align_t"}},
+	{"vector", []string{"std::vector"}},
+	{"string", []string{"std::string", "std::wstring", "std::u16string", "std::u32string", "std::basic_string", "std::to_string", "std::to_wstring", "std::stoi", "std::stol", "std::stoll", "std::stoul", "std::stoull", "std::stof", "std::stod", "std::getline", "std::char_traits"}},
+	{"string_view", []string{"std::string_view", "std::wstring_view", "std::basic_string_view"}},
+	{"memory", []string{"std::unique_ptr", "std::shared_ptr", "std::weak_ptr", "std::make_unique", "std::make_shared", "std::allocator", "std::enable_shared_from_this", "std::addressof"}},
+	{"map", []string{"std::map", "std::multimap"}},
+	{"unordered_map", []string{"std::unordered_map", "std::unordered_multimap"}},
+	{"set", []string{"std::set", "std::multiset"}},
+	{"unordered_set", []string{"std::unordered_set", "std::unordered_multiset"}},
+	{"list", []string{"std::list"}},
+	{"forward_list", []string{"std::forward_list"}},
+	{"deque", []string{"std::deque"}},
+	{"queue", []string{"std::queue", "std::priority_queue"}},
+	{"stack", []string{"std::stack"}},
+	{"array", []string{"std::array"}},
+	{"bitset", []string{"std::bitset"}},
+	{"span", []string{"std::span"}},
+	{"iostream", []string{"std::cout", "std::cin", "std::cerr", "std::clog", "std::wcout", "std::wcin", "std::wcerr"}},
+	{"ostream", []string{"std::ostream", "std::endl", "std::flush", "std::ends"}},
+	{"istream", []string{"std::istream", "std::iostream", "std::ws", "std::getline"}},
+	{"sstream", []string{"std::stringstream", "std::istringstream", "std::ostringstream"}},
+	{"fstream", []string{"std::fstream", "std::ifstream", "std::ofstream"}},
+	{"iomanip", []string{"std::setw", "std::setprecision", "std::setfill", "std::put_time", "std::quoted"}},
+	{"algorithm", []string{"std::sort", "std::stable_sort", "std::find", "std::find_if", "std::copy", "std::copy_if", "std::transform", "std::min", "std::max", "std::minmax", "std::min_element", "std::max_element", "std::clamp", "std::for_each", "std::remove", "std::remove_if", "std::reverse", "std::unique", "std::fill", "std::count", "std::count_if", "std::any_of", "std::all_of", "std::none_of", "std::binary_search", "std::lower_bound", "std::upper_bound", "std::equal"}},
+	{"numeric", []string{"std::accumulate", "std::iota", "std::reduce", "std::inner_product", "std::partial_sum", "std::gcd", "std::lcm"}},
+	{"iterator", []string{"std::back_inserter", "std::inserter", "std::next", "std::prev", "std::distance", "std::advance", "std::iterator_traits", "std::reverse_iterator"}},
+	{"utility", []string{"std::pair", "std::make_pair", "std::move", "std::forward", "std::swap", "std::exchange", "std::declval", "std::index_sequence"}},
+	{"tuple", []string{"std::tuple", "std::make_tuple", "std::tie", "std::tuple_size", "std::apply"}},
+	{"functional", []string{"std::function", "std::bind", "std::ref", "std::cref", "std::hash", "std::invoke", "std::less", "std::greater", "std::plus"}},
+	{"optional", []string{"std::optional", "std::nullopt", "std::make_optional"}},
+	{"variant", []string{"std::variant", "std::visit", "std::holds_alternative", "std::get_if", "std::monostate"}},
+	{"any", []string{"std::any", "std::any_cast", "std::make_any"}},
+	{"thread", []string{"std::thread", "std::this_thread", "std::jthread"}},
+	{"mutex", []string{"std::mutex", "std::recursive_mutex", "std::timed_mutex", "std::lock_guard", "std::unique_lock", "std::scoped_lock", "std::call_once", "std::once_flag"}},
+	{"shared_mutex", []string{"std::shared_mutex", "std::shared_lock"}},
+	{"condition_variable", []string{"std::condition_variable", "std::condition_variable_any"}},
+	{"future", []string{"std::future", "std::promise", "std::async", "std::packaged_task", "std::shared_future"}},
+	{"atomic", []string{"std::atomic", "std::atomic_flag", "std::memory_order"}},
+	{"chrono", []string{"std::chrono"}},
+	{"exception", []string{"std::exception", "std::exception_ptr", "std::current_exception", "std::rethrow_exception", "std::terminate"}},
+	{"stdexcept", []string{"std::runtime_error", "std::logic_error", "std::invalid_argument", "std::out_of_range", "std::length_error", "std::domain_error", "std::overflow_error", "std::underflow_error", "std::range_error"}},
+	{"system_error", []string{"std::system_error", "std::error_code", "std::error_category", "std::errc"}},
+	{"limits", []string{"std::numeric_limits"}},
+	{"type_traits", []string{"std::is_same", "std::is_same_v", "std::enable_if", "std::enable_if_t", "std::decay", "std::decay_t", "std::remove_reference", "std::remove_reference_t", "std::conditional", "std::conditional_t", "std::is_integral", "std::is_integral_v", "std::is_floating_point", "std::integral_constant", "std::true_type", "std::false_type"}},
+	{"initializer_list", []string{"std::initializer_list"}},
+	{"filesystem", []string{"std::filesystem"}},
+	{"random", []string{"std::mt19937", "std::mt19937_64", "std::random_device", "std::default_random_engine", "std::uniform_int_distribution", "std::uniform_real_distribution", "std::normal_distribution", "std::bernoulli_distribution"}},
+	{"regex", []string{"std::regex", "std::smatch", "std::cmatch", "std::regex_match", "std::regex_search", "std::regex_replace"}},
+	{"complex", []string{"std::complex"}},
+	{"valarray", []string{"std::valarray"}},
+	{"typeinfo", []string{"std::type_info", "std::bad_cast"}},
+	{"typeindex", []string{"std::type_index"}},
+	{"new", []string{"std::bad_alloc", "std::nothrow", "std::launder"}},
+	{"ratio", []string{"std::ratio"}},
+	{"format", []string{"std::format", "std::format_to", "std::vformat"}},
+	{"charconv", []string{"std::to_chars", "std::from_chars"}},
+	{"numbers", []string{"std::numbers"}},
+	{"source_location", []string{"std::source_location"}},
+}
+
+// cMacros are symbols of the C headers that are macros, and are not found in namespace std
+var cMacros = map[string]bool{
+	"NULL": true, "offsetof": true, "assert": true, "errno": true, "va_start": true, "va_end": true, "va_arg": true,
+	"va_copy": true, "setjmp": true, "stdin": true, "stdout": true, "stderr": true,
+}
+
+// symbolHeaders maps a symbol, like "printf" or "std::vector", to the headers that declare it,
+// the canonical header first. It is built from standardHeaderSymbols the first time it is needed.
+var (
+	symbolHeaders     map[string][]string
+	symbolHeadersOnce sync.Once
+)
+
+// standardHeadersFor returns the standard headers that declare the given symbol, like "std::vector" or
+// "uint32_t", with the canonical header first. C symbols that are used as "std::printf" are found in the
+// C++ headers, like cstdio.
+func standardHeadersFor(symbol string) []string {
+	symbolHeadersOnce.Do(func() {
+		m := make(map[string][]string)
+		for _, entry := range standardHeaderSymbols {
+			for _, symbol := range entry.symbols {
+				m[symbol] = appendUnique(m[symbol], entry.header)
+				cxxHeader, ok := cxxHeadersForC[entry.header]
+				if ok && !cMacros[symbol] && strings.ToUpper(symbol) != symbol {
+					m["std::"+symbol] = appendUnique(m["std::"+symbol], cxxHeader)
+				}
+			}
+		}
+		symbolHeaders = m
+	})
+	return symbolHeaders[symbol]
+}
+
+// StandardHeaderFor returns the canonical standard header for a symbol, like "vector" for "std::vector"
+// or "stdio.h" for "printf". For C++, the C++ version of a C header is returned, like "cstdio" for "printf".
+func StandardHeaderFor(symbol string, lang Language) (string, bool) {
+	headers := standardHeadersFor(symbol)
+	if len(headers) == 0 {
+		return "", false
+	}
+	header := headers[0]
+	if cxxHeader, ok := cxxHeadersForC[header]; ok && lang == LanguageCXX {
+		return cxxHeader, true
+	}
+	return header, true
+}
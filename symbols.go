@@ -0,0 +1,538 @@
+package autocpp
+
+import (
+	"sort"
+	"strings"
+)
+
+// HeaderSymbols are the symbols that a header declares at namespace scope
+type HeaderSymbols struct {
+	Functions []string `json:"functions,omitempty"`
+	Classes   []string `json:"classes,omitempty"` // classes, structs, unions and enums
+	Macros    []string `json:"macros,omitempty"`
+	Typedefs  []string `json:"typedefs,omitempty"`  // typedef and using declarations
+	Variables []string `json:"variables,omitempty"` // global variables and enumerators
+}
+
+// Names returns all symbol names
+func (symbols HeaderSymbols) Names() []string {
+	var names []string
+	for _, xs := range [][]string{symbols.Functions, symbols.Classes, symbols.Macros, symbols.Typedefs, symbols.Variables} {
+		names = append(names, xs...)
+	}
+	return names
+}
+
+// Empty checks if no symbols were found
+func (symbols HeaderSymbols) Empty() bool {
+	return len(symbols.Names()) == 0
+}
+
+// cppKeywords are keywords that can come right before a "(" or an identifier, and that are not names
+var cppKeywords = map[string]bool{
+	"alignas": true, "alignof": true, "asm": true, "auto": true, "bool": true, "break": true, "case": true,
+	"catch": true, "char": true, "class": true, "const": true, "consteval": true, "constexpr": true,
+	"constinit": true, "continue": true, "decltype": true, "default": true, "delete": true, "do": true,
+	"double": true, "else": true, "enum": true, "explicit": true, "extern": true, "final": true, "float": true,
+	"for": true, "friend": true, "if": true, "inline": true, "int": true, "long": true, "mutable": true,
+	"namespace": true, "new": true, "noexcept": true, "operator": true, "override": true, "private": true,
+	"protected": true, "public": true, "register": true, "restrict": true, "return": true, "short": true,
+	"signed": true, "sizeof": true, "static": true, "static_assert": true, "struct": true, "switch": true,
+	"template": true, "this": true, "throw": true, "typedef": true, "typename": true, "union": true,
+	"unsigned": true, "using": true, "virtual": true, "void": true, "volatile": true, "while": true,
+	"__attribute__": true, "__declspec": true, "__extension__": true, "__restrict": true, "__inline": true,
+}
+
+// isIdentifier checks if the given token is an identifier that is not a keyword
+func isIdentifier(token string) bool {
+	if token == "" || cppKeywords[token] {
+		return false
+	}
+	c := token[0]
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isIdentifierByte checks if the given byte can be part of an identifier
+func isIdentifierByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// stripComments replaces comments with spaces and the contents of string and character literals
+// with nothing, while keeping the line breaks, so that line numbers stay the same
+func stripComments(data []byte) string {
+	var sb strings.Builder
+	sb.Grow(len(data))
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				// A backslash at the end of the line continues the comment
+				if data[i] == '\\' && i+1 < len(data) && data[i+1] == '\n' {
+					sb.WriteByte('\n')
+					i++
+				}
+				i++
+			}
+			if i < len(data) {
+				sb.WriteByte('\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i < len(data) && !(data[i] == '*' && i+1 < len(data) && data[i+1] == '/') {
+				if data[i] == '\n' {
+					sb.WriteByte('\n')
+				}
+				i++
+			}
+			i++
+			sb.WriteByte(' ')
+		case c == '"' || c == '\'':
+			sb.WriteByte(c)
+			for i++; i < len(data) && data[i] != c && data[i] != '\n'; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+			sb.WriteByte(c)
+			if i < len(data) && data[i] == '\n' {
+				sb.WriteByte('\n')
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// logicalLines splits stripped source code into lines, joining lines that end with a backslash.
+// The joined lines are followed by empty lines, so that the line numbers stay the same.
+func logicalLines(code string) []string {
+	lines := strings.Split(code, "\n")
+	for i := 0; i < len(lines); i++ {
+		for j := i; strings.HasSuffix(strings.TrimRight(lines[i], " \t\r"), "\\") && j+1 < len(lines); j++ {
+			lines[i] = strings.TrimSuffix(strings.TrimRight(lines[i], " \t\r"), "\\") + " " + lines[j+1]
+			lines[j+1] = ""
+		}
+	}
+	return lines
+}
+
+// directive returns the name and the rest of a preprocessor directive, like "define" and "MAX(a, b) ...",
+// and false if the line is not a directive
+func directive(line string) (string, string, bool) {
+	trimmedLine := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmedLine, "#") {
+		return "", "", false
+	}
+	trimmedLine = strings.TrimSpace(trimmedLine[1:])
+	end := 0
+	for end < len(trimmedLine) && isIdentifierByte(trimmedLine[end]) {
+		end++
+	}
+	return trimmedLine[:end], strings.TrimSpace(trimmedLine[end:]), true
+}
+
+// firstIdentifier returns the identifier at the start of the given string
+func firstIdentifier(s string) string {
+	end := 0
+	for end < len(s) && isIdentifierByte(s[end]) {
+		end++
+	}
+	return s[:end]
+}
+
+// tokenize splits code into identifiers, numbers, "::" and single characters
+func tokenize(code string) []string {
+	var tokens []string
+	for i := 0; i < len(code); {
+		c := code[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			i++
+		case isIdentifierByte(c):
+			start := i
+			for i < len(code) && isIdentifierByte(code[i]) {
+				i++
+			}
+			tokens = append(tokens, code[start:i])
+		case c == ':' && i+1 < len(code) && code[i+1] == ':':
+			tokens = append(tokens, "::")
+			i += 2
+		case c == '"' || c == '\'':
+			// The contents have already been stripped
+			end := i + 2
+			if end > len(code) {
+				end = len(code)
+			}
+			tokens = append(tokens, code[i:end])
+			i = end
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens
+}
+
+// Identifiers returns the identifiers that are used in the given source code, outside of comments,
+// string literals and #include lines
+func Identifiers(data []byte) map[string]bool {
+	identifiers := make(map[string]bool)
+	for _, line := range logicalLines(stripComments(data)) {
+		if name, _, ok := directive(line); ok && (name == "include" || name == "include_next") {
+			continue
+		}
+		for _, token := range tokenize(line) {
+			if isIdentifier(token) {
+				identifiers[token] = true
+			}
+		}
+	}
+	return identifiers
+}
+
+// symbolScope is a { } block in a header
+type symbolScope struct {
+	kind      string   // "namespace" for namespaces and extern "C", "type" for class and enum bodies, or "other"
+	enum      bool     // true for enum bodies, where the enumerators are declared
+	statement []string // the statement that the block is part of
+}
+
+// ParseHeaderSymbols finds the functions, classes, macros, typedefs and variables that the given header
+// declares. This is a lightweight parser that does not run the preprocessor, so declarations that are
+// made by macros are not found, and declarations in all branches of #if blocks are found.
+func ParseHeaderSymbols(data []byte) HeaderSymbols {
+	var (
+		symbols    HeaderSymbols
+		code       strings.Builder
+		lastIfndef string
+	)
+	for _, line := range logicalLines(stripComments(data)) {
+		name, rest, ok := directive(line)
+		if !ok {
+			code.WriteString(line)
+			code.WriteByte('\n')
+			continue
+		}
+		code.WriteByte('\n')
+		switch name {
+		case "ifndef":
+			lastIfndef = firstIdentifier(rest)
+			continue
+		case "define":
+			macro := firstIdentifier(rest)
+			// The include guard is not a symbol that is meant to be used
+			if macro != "" && macro != lastIfndef {
+				symbols.Macros = append(symbols.Macros, macro)
+			}
+		}
+		lastIfndef = ""
+	}
+	var (
+		scopes    []symbolScope
+		statement []string
+	)
+	transparent := func() bool {
+		for _, scope := range scopes {
+			if scope.kind != "namespace" {
+				return false
+			}
+		}
+		return true
+	}
+	for _, token := range tokenize(code.String()) {
+		switch token {
+		case "{":
+			kind := "other"
+			switch {
+			case len(statement) > 0 && statement[0] == "namespace", len(statement) > 1 && statement[0] == "extern" && statement[1] == `""`:
+				kind = "namespace"
+			case typeKeyword(statement) != "":
+				kind = "type"
+				if transparent() {
+					if name := typeName(statement); name != "" {
+						symbols.Classes = append(symbols.Classes, name)
+					}
+				}
+			default:
+				if transparent() {
+					if name := functionName(statement); name != "" {
+						symbols.Functions = append(symbols.Functions, name)
+					}
+				}
+			}
+			scopes = append(scopes, symbolScope{kind: kind, enum: typeKeyword(statement) == "enum", statement: statement})
+			statement = nil
+		case "}":
+			if len(scopes) == 0 {
+				statement = nil
+				continue
+			}
+			scope := scopes[len(scopes)-1]
+			scopes = scopes[:len(scopes)-1]
+			if scope.enum && transparent() {
+				symbols.Variables = append(symbols.Variables, enumerators(statement)...)
+			}
+			statement = nil
+			if scope.kind == "type" || hasS(scope.statement, "=") {
+				// Like "typedef struct { ... } name;" or "int xs[] = { ... };", the statement continues after the body
+				statement = append(append([]string{}, scope.statement...), "{", "}")
+			}
+		case ";":
+			if transparent() {
+				declaration(&symbols, statement)
+			}
+			statement = nil
+		default:
+			statement = append(statement, token)
+		}
+	}
+	for _, xs := range []*[]string{&symbols.Functions, &symbols.Classes, &symbols.Macros, &symbols.Typedefs, &symbols.Variables} {
+		*xs = sortedUnique(*xs)
+	}
+	return symbols
+}
+
+// sortedUnique sorts the given strings and removes duplicates
+func sortedUnique(xs []string) []string {
+	sort.Strings(xs)
+	var result []string
+	for i, x := range xs {
+		if i == 0 || x != xs[i-1] {
+			result = append(result, x)
+		}
+	}
+	return result
+}
+
+// skipTemplate returns the statement without a leading "template <...>"
+func skipTemplate(statement []string) []string {
+	if len(statement) < 2 || statement[0] != "template" || statement[1] != "<" {
+		return statement
+	}
+	depth := 0
+	for i, token := range statement[1:] {
+		switch token {
+		case "<":
+			depth++
+		case ">":
+			depth--
+			if depth == 0 {
+				return statement[i+2:]
+			}
+		}
+	}
+	return nil
+}
+
+// typeKeyword returns "class", "struct", "union" or "enum" if the statement defines a type, before any "("
+func typeKeyword(statement []string) string {
+	statement = skipTemplate(statement)
+	if len(statement) > 0 && statement[0] == "typedef" {
+		statement = statement[1:]
+	}
+	for _, token := range statement {
+		switch token {
+		case "class", "struct", "union", "enum":
+			return token
+		case "(", "=":
+			return ""
+		}
+	}
+	return ""
+}
+
+// typeName returns the name that follows class, struct, union or enum in the statement,
+// skipping attributes and "enum class"
+func typeName(statement []string) string {
+	statement = skipTemplate(statement)
+	for i, token := range statement {
+		switch token {
+		case "class", "struct", "union", "enum":
+		default:
+			continue
+		}
+		depth := 0
+		for _, next := range statement[i+1:] {
+			switch {
+			case next == "(" || next == "[":
+				depth++
+			case next == ")" || next == "]":
+				depth--
+			case depth > 0, next == "class", next == "struct":
+			case isIdentifier(next) && !strings.HasPrefix(next, "__"):
+				return next
+			case next == ":" || next == "{" || next == "<":
+				return ""
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+// functionName returns the name of the function that the statement declares, which is the identifier
+// before the first "(", or "" if the statement does not look like a function declaration
+func functionName(statement []string) string {
+	statement = skipTemplate(statement)
+	for i, token := range statement {
+		if token == "(" {
+			if i < 2 || !isIdentifier(statement[i-1]) || statement[i-2] == "::" || strings.HasPrefix(statement[i-1], "__") {
+				// A macro call, a function pointer, a member function or something that is not a function
+				return ""
+			}
+			if prev := statement[i-2]; !isIdentifier(prev) && !cppKeywords[prev] && prev != "*" && prev != "&" && prev != ">" {
+				return ""
+			}
+			if i+1 < len(statement) && statement[i+1] == "(" && isIdentifier(statement[i-2]) {
+				// The parameters are wrapped in a macro, like "int deflate OF((z_streamp strm, int flush))"
+				return statement[i-2]
+			}
+			return statement[i-1]
+		}
+		if token == "=" || token == "{" {
+			return ""
+		}
+	}
+	return ""
+}
+
+// enumerators returns the enumerators in the body of an enum, like "RED" and "GREEN" for "RED = 1, GREEN"
+func enumerators(body []string) []string {
+	var names []string
+	depth := 0
+	expectName := true
+	for _, token := range body {
+		switch token {
+		case "(", "[", "<":
+			depth++
+		case ")", "]", ">":
+			depth--
+		case ",":
+			if depth == 0 {
+				expectName = true
+			}
+			continue
+		}
+		if expectName && depth == 0 && isIdentifier(token) {
+			names = append(names, token)
+		}
+		expectName = false
+	}
+	return names
+}
+
+// declaration adds the symbols that are declared by a statement that ends with ";"
+func declaration(symbols *HeaderSymbols, statement []string) {
+	statement = skipTemplate(statement)
+	if len(statement) == 0 {
+		return
+	}
+	switch statement[0] {
+	case "typedef":
+		if name := typedefName(statement[1:]); name != "" {
+			symbols.Typedefs = append(symbols.Typedefs, name)
+		}
+		return
+	case "using":
+		// "using name = type;" and "using ::printf;", but not "using namespace std;"
+		if len(statement) > 2 && statement[2] == "=" && isIdentifier(statement[1]) {
+			symbols.Typedefs = append(symbols.Typedefs, statement[1])
+		} else if statement[1] != "namespace" && isIdentifier(statement[len(statement)-1]) {
+			symbols.Typedefs = append(symbols.Typedefs, statement[len(statement)-1])
+		}
+		return
+	case "static_assert", "friend", "namespace":
+		return
+	}
+	if typeKeyword(statement) != "" && !hasS(statement, "{") {
+		// A forward declaration, like "class Widget;"
+		if name := typeName(statement); name != "" && statement[len(statement)-1] == name {
+			symbols.Classes = append(symbols.Classes, name)
+			return
+		}
+	}
+	if name := functionName(statement); name != "" {
+		symbols.Functions = append(symbols.Functions, name)
+		return
+	}
+	// A variable, like "extern int counter;" or "struct { ... } settings;"
+	if len(statement) > 1 && !hasS(statement, "(") {
+		end := len(statement)
+		if i := indexS(statement, "="); i >= 0 {
+			end = i
+		}
+		if i := indexS(statement[:end], "["); i >= 0 {
+			end = i
+		}
+		if end > 1 && isIdentifier(statement[end-1]) && statement[end-2] != "::" {
+			symbols.Variables = append(symbols.Variables, statement[end-1])
+		}
+	}
+}
+
+// typedefName returns the name that a typedef declares, like "callback" for "void (*callback)(int)"
+func typedefName(statement []string) string {
+	for i := 0; i+2 < len(statement); i++ {
+		if statement[i] == "(" && (statement[i+1] == "*" || statement[i+1] == "^") && isIdentifier(statement[i+2]) {
+			return statement[i+2]
+		}
+	}
+	end := len(statement)
+	if i := indexS(statement, "["); i >= 0 {
+		end = i
+	}
+	if end > 0 && isIdentifier(statement[end-1]) {
+		return statement[end-1]
+	}
+	return ""
+}
+
+// indexS returns the index of e in xs, or -1
+func indexS(xs []string, e string) int {
+	for i, x := range xs {
+		if x == e {
+			return i
+		}
+	}
+	return -1
+}
+
+// conditionalLines returns the line numbers, starting at 1, of the lines that are within #if, #ifdef or
+// #ifndef blocks. The include guard of a header does not count as a conditional block.
+func conditionalLines(data []byte) map[int]bool {
+	lines := logicalLines(stripComments(data))
+	conditional := make(map[int]bool)
+	depth := 0
+	guard := false
+	first := true
+	for i, line := range lines {
+		name, rest, ok := directive(line)
+		if depth > 0 && !(guard && depth == 1) {
+			conditional[i+1] = true
+		}
+		if !ok {
+			continue
+		}
+		switch name {
+		case "if", "ifdef", "ifndef":
+			if first && name == "ifndef" {
+				// An include guard is #ifndef NAME followed by #define NAME
+				for _, next := range lines[i+1:] {
+					if nextName, nextRest, ok := directive(next); ok {
+						guard = nextName == "define" && firstIdentifier(nextRest) == firstIdentifier(rest)
+						break
+					}
+				}
+			}
+			depth++
+		case "endif":
+			if depth > 0 {
+				depth--
+			}
+		}
+		first = false
+	}
+	return conditional
+}
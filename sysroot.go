@@ -0,0 +1,104 @@
+package autocpp
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Sysroot returns the root directory that system include directories and package databases are
+// resolved under, or an empty string if the LocalSystem is not using a sysroot.
+func (locsys *LocalSystem) Sysroot() string {
+	return locsys.sysroot
+}
+
+// Target returns the target triple, like "aarch64-linux-gnu" or "x86_64-w64-mingw32",
+// or an empty string if the LocalSystem is for the host system.
+func (locsys *LocalSystem) Target() string {
+	return locsys.target
+}
+
+// TargetOS returns the operating system of the target triple, using the same names as runtime.GOOS.
+// If no target triple has been given, runtime.GOOS is returned.
+func (locsys *LocalSystem) TargetOS() string {
+	return targetOS(locsys.target)
+}
+
+func targetOS(target string) string {
+	target = strings.ToLower(target)
+	switch {
+	case target == "":
+		return runtime.GOOS
+	case strings.Contains(target, "mingw"), strings.Contains(target, "windows"), strings.Contains(target, "cygwin"), strings.Contains(target, "msvc"):
+		return "windows"
+	case strings.Contains(target, "darwin"), strings.Contains(target, "apple"), strings.Contains(target, "macos"):
+		return "darwin"
+	case strings.Contains(target, "freebsd"):
+		return "freebsd"
+	case strings.Contains(target, "netbsd"):
+		return "netbsd"
+	case strings.Contains(target, "openbsd"):
+		return "openbsd"
+	case strings.Contains(target, "linux"), strings.Contains(target, "android"):
+		return "linux"
+	}
+	return runtime.GOOS
+}
+
+// Root resolves the given system path under the sysroot, if there is one.
+// Like for GCC, a leading "=" or "$SYSROOT" in a directory given by the user also refers to the sysroot,
+// while a plain directory given by the user is used as it is. Paths that are already within the
+// sysroot are returned unchanged.
+func (locsys *LocalSystem) Root(path string) string {
+	if locsys.sysroot == "" || path == locsys.sysroot || strings.HasPrefix(path, locsys.sysroot+string(filepath.Separator)) {
+		return path
+	}
+	return filepath.Join(locsys.sysroot, path)
+}
+
+// userDirectory handles the "=" and "$SYSROOT" prefixes for a directory given by the user
+func (locsys *LocalSystem) userDirectory(dir string) string {
+	for _, prefix := range []string{"=", "$SYSROOT"} {
+		if strings.HasPrefix(dir, prefix) {
+			return locsys.Root(filepath.Join("/", strings.TrimPrefix(dir, prefix)))
+		}
+	}
+	return dir
+}
+
+// userDirectories calls userDirectory for each of the given directories
+func (locsys *LocalSystem) userDirectories(dirs []string) []string {
+	xs := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		xs = append(xs, locsys.userDirectory(dir))
+	}
+	return xs
+}
+
+// PkgConfigDirectories returns the directories where pkg-config should look for .pc files
+// for the target system, resolved under the sysroot.
+func (locsys *LocalSystem) PkgConfigDirectories() []string {
+	var xs []string
+	if locsys.target != "" {
+		xs = append(xs, locsys.Root(filepath.Join("/usr/lib", locsys.target, "pkgconfig")))
+		xs = append(xs, locsys.Root(filepath.Join("/usr", locsys.target, "lib", "pkgconfig")))
+	}
+	xs = append(xs, locsys.Root("/usr/lib/pkgconfig"), locsys.Root("/usr/share/pkgconfig"))
+	if locsys.sysroot == "" {
+		xs = append(xs, "/usr/local/lib/pkgconfig")
+	}
+	return xs
+}
+
+// PackageConfig returns a PackageConfig for running pkg-config against the sysroot of this LocalSystem
+func (locsys *LocalSystem) PackageConfig() *PackageConfig {
+	if locsys.sysroot == "" && locsys.target == "" {
+		return NewPackageConfig()
+	}
+	return &PackageConfig{sysroot: locsys.sysroot, libdirs: locsys.PkgConfigDirectories()}
+}
+
+// Pacman returns a Pacman that uses the package database within the sysroot of this LocalSystem
+func (locsys *LocalSystem) Pacman() *Pacman {
+	return NewPacman(locsys.sysroot)
+}
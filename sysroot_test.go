@@ -0,0 +1,69 @@
+package autocpp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFiles(t *testing.T, rootDirectory string, filenames ...string) {
+	t.Helper()
+	for _, filename := range filenames {
+		path := filepath.Join(rootDirectory, filepath.FromSlash(filename))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSysrootCrossLinux(t *testing.T) {
+	sysroot := t.TempDir()
+	writeTestFiles(t, sysroot, "usr/include/stdio.h", "usr/include/aarch64-linux-gnu/bits/wordsize.h", "usr/include/c++/12/vector")
+	locsys, err := NewLocalSystemWithOptions(LocalSystemOptions{Sysroot: sysroot, Target: "aarch64-linux-gnu", IgnoreEnvironment: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locsys.TargetOS() != "linux" {
+		t.Errorf("expected the target OS to be linux, got %s", locsys.TargetOS())
+	}
+	for _, expected := range []string{"usr/include/stdio.h", "usr/include/aarch64-linux-gnu/bits/wordsize.h", "usr/include/c++/12/vector"} {
+		if !hasS(locsys.IncludeFiles(), filepath.Join(sysroot, filepath.FromSlash(expected))) {
+			t.Errorf("expected %s to be found within the sysroot, got %v", expected, locsys.IncludeFiles())
+		}
+	}
+	if got := locsys.Root("/usr/include"); got != filepath.Join(sysroot, "usr", "include") {
+		t.Errorf("unexpected root directory: %s", got)
+	}
+	if got := locsys.userDirectory("=/opt/sdk/include"); got != filepath.Join(sysroot, "opt", "sdk", "include") {
+		t.Errorf("unexpected user directory: %s", got)
+	}
+	pc := locsys.PackageConfig()
+	environ := pc.Environ()
+	if !hasS(environ, "PKG_CONFIG_SYSROOT_DIR="+sysroot) {
+		t.Errorf("expected PKG_CONFIG_SYSROOT_DIR to be set to the sysroot")
+	}
+	if !hasS(environ, "PKG_CONFIG_LIBDIR="+filepath.Join(sysroot, "usr/lib/aarch64-linux-gnu/pkgconfig")+string(filepath.ListSeparator)+filepath.Join(sysroot, "usr/aarch64-linux-gnu/lib/pkgconfig")+string(filepath.ListSeparator)+filepath.Join(sysroot, "usr/lib/pkgconfig")+string(filepath.ListSeparator)+filepath.Join(sysroot, "usr/share/pkgconfig")) {
+		t.Errorf("unexpected PKG_CONFIG_LIBDIR in %v", environ)
+	}
+}
+
+func TestSysrootMingw(t *testing.T) {
+	sysroot := t.TempDir()
+	writeTestFiles(t, sysroot, "usr/x86_64-w64-mingw32/include/windows.h", "usr/x86_64-w64-mingw32/include/d3d11.h")
+	locsys, err := NewLocalSystemWithOptions(LocalSystemOptions{Sysroot: sysroot, Target: "x86_64-w64-mingw32", IgnoreEnvironment: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locsys.TargetOS() != "windows" {
+		t.Errorf("expected the target OS to be windows, got %s", locsys.TargetOS())
+	}
+	if !hasS(locsys.CommonIncludes(), "windows.h") {
+		t.Errorf("expected the common includes for Windows to be used")
+	}
+	if !hasS(locsys.IncludeFiles(), filepath.Join(sysroot, "usr", "x86_64-w64-mingw32", "include", "windows.h")) {
+		t.Errorf("expected windows.h to be found within the sysroot, got %v", locsys.IncludeFiles())
+	}
+}
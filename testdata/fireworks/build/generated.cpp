@@ -0,0 +1 @@
+#include <generated_by_cmake.h>
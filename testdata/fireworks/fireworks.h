@@ -0,0 +1,10 @@
+#pragma once
+
+#include <cstdint>
+
+#include "particle.h"
+
+struct Firework {
+    uint32_t color;
+    Particle particles[64];
+};
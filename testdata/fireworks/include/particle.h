@@ -0,0 +1,7 @@
+#pragma once
+
+#include <cmath>
+
+struct Particle {
+    float x, y, dx, dy;
+};
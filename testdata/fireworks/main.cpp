@@ -0,0 +1,14 @@
+#include <iostream>
+#include <thread>
+#include <vector>
+
+#include <SDL2/SDL.h>
+
+#include "fireworks.h"
+
+int main(int argc, char* argv[])
+{
+    std::vector<Firework> fireworks;
+    std::cout << "Fireworks!" << std::endl;
+    return 0;
+}
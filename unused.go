@@ -0,0 +1,240 @@
+package autocpp
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Confidence is how sure a diagnostic is
+type Confidence int
+
+const (
+	// ConfidenceLow is for diagnostics that are likely to be wrong, like when no symbols were found in a header
+	ConfidenceLow Confidence = iota
+	// ConfidenceMedium is for diagnostics that depend on headers that could not be fully indexed,
+	// or on lines within #if blocks
+	ConfidenceMedium
+	// ConfidenceHigh is for diagnostics where all the involved headers were indexed
+	ConfidenceHigh
+)
+
+var confidenceNames = []string{
+	ConfidenceLow:    "low",
+	ConfidenceMedium: "medium",
+	ConfidenceHigh:   "high",
+}
+
+// String returns "low", "medium" or "high"
+func (confidence Confidence) String() string {
+	if confidence < 0 || int(confidence) >= len(confidenceNames) {
+		return "unknown"
+	}
+	return confidenceNames[confidence]
+}
+
+// MarshalText makes the confidence appear by name in JSON
+func (confidence Confidence) MarshalText() ([]byte, error) {
+	return []byte(confidence.String()), nil
+}
+
+// ParseConfidence parses "low", "medium" or "high"
+func ParseConfidence(s string) (Confidence, error) {
+	for i, name := range confidenceNames {
+		if strings.EqualFold(s, name) {
+			return Confidence(i), nil
+		}
+	}
+	return ConfidenceLow, fmt.Errorf("unknown confidence level %q, expected one of: %s", s, strings.Join(confidenceNames, ", "))
+}
+
+// maxIndexedHeaders is the maximum number of headers that are indexed for a single include,
+// including the headers that it includes
+const maxIndexedHeaders = 512
+
+// UnusedInclude is an #include line in a translation unit where none of the symbols that the included
+// header declares, directly or through the headers it includes, are used
+type UnusedInclude struct {
+	Include
+	// Header is the path of the included header
+	Header string `json:"header"`
+	// Symbols is the number of symbols that were found in the header and the headers it includes
+	Symbols    int        `json:"symbols"`
+	Confidence Confidence `json:"confidence"`
+	Reason     string     `json:"reason"`
+}
+
+// indexedHeader is a header that has been parsed by a symbolIndex
+type indexedHeader struct {
+	symbols  HeaderSymbols
+	includes []Include
+	size     int64 // the number of bytes
+	lines    int
+	ok       bool // false if the header could not be read
+}
+
+// symbolIndex finds and caches the symbols of project and system headers
+type symbolIndex struct {
+	src       *Sources
+	locsys    *LocalSystem
+	headers   map[string]*indexedHeader
+	noSymbols bool // only find the includes and the sizes of the headers
+}
+
+// newSymbolIndex returns a symbolIndex for the given sources. locsys may be nil, and then only
+// project headers are indexed.
+func newSymbolIndex(src *Sources, locsys *LocalSystem) *symbolIndex {
+	return &symbolIndex{src: src, locsys: locsys, headers: make(map[string]*indexedHeader)}
+}
+
+// header reads and parses the given header, or returns the cached result
+func (index *symbolIndex) header(path string) *indexedHeader {
+	if h, ok := index.headers[path]; ok {
+		return h
+	}
+	h := &indexedHeader{}
+	var data []byte
+	if f, ok := index.src.files[path]; ok {
+		data, h.ok = f.data, true
+	} else if index.locsys != nil {
+		var err error
+		data, err = fs.ReadFile(index.locsys.fsys, index.locsys.fsPath(path))
+		h.ok = err == nil
+	}
+	if h.ok {
+		if !index.noSymbols {
+			h.symbols = ParseHeaderSymbols(data)
+		}
+		h.includes = parseIncludes(path, data)
+		h.size, h.lines = int64(len(data)), countLines(data)
+	}
+	index.headers[path] = h
+	return h
+}
+
+// resolve finds the header that an #include line refers to, first among the project files
+// and then among the system include files
+func (index *symbolIndex) resolve(include Include) (string, bool) {
+	if _, ok := index.src.files[include.File]; ok {
+		if target, ok := index.src.ResolveProjectInclude(include); ok {
+			return target, true
+		}
+		if found, ok := index.src.foundMap[include.Name]; ok {
+			return found, true
+		}
+	}
+	if index.locsys == nil {
+		return "", false
+	}
+	if !include.System {
+		if p := filepath.Join(filepath.Dir(include.File), filepath.FromSlash(include.Name)); index.locsys.HasIncludeFile(p) {
+			return p, true
+		}
+	}
+	return index.locsys.FindIncludeFile(include.Name)
+}
+
+// symbols returns the names of the symbols that the given header declares, including the symbols of
+// the headers that it includes. complete is false if some of the headers could not be found or read,
+// or if there were too many headers.
+func (index *symbolIndex) symbols(path string) (names map[string]bool, complete bool) {
+	names = make(map[string]bool)
+	complete = true
+	visited := map[string]bool{path: true}
+	queue := []string{path}
+	for len(queue) > 0 {
+		if len(visited) > maxIndexedHeaders {
+			complete = false
+			break
+		}
+		current := queue[0]
+		queue = queue[1:]
+		h := index.header(current)
+		if !h.ok {
+			complete = false
+			continue
+		}
+		for _, name := range h.symbols.Names() {
+			names[name] = true
+		}
+		for _, include := range h.includes {
+			target, ok := index.resolve(include)
+			if !ok {
+				complete = false
+				continue
+			}
+			if !visited[target] {
+				visited[target] = true
+				queue = append(queue, target)
+			}
+		}
+	}
+	return names, complete
+}
+
+// isOwnHeader checks if the header has the same name as the source file, like widget.h for widget.cpp
+func isOwnHeader(sourceFile, header string) bool {
+	trimExt := func(p string) string {
+		base := filepath.Base(p)
+		return strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	return trimExt(sourceFile) == trimExt(header)
+}
+
+// UnusedIncludes finds #include lines in the C and C++ source files where none of the symbols that the
+// included header declares are referenced by the source file. This is a lightweight check that does not
+// run the preprocessor, so each result has a confidence level. The own header of a source file, like
+// widget.h for widget.cpp, is never reported. locsys may be nil, and then only project headers are checked.
+// The results are ordered by file and then by line.
+func (src *Sources) UnusedIncludes(locsys *LocalSystem) []UnusedInclude {
+	if locsys != nil {
+		src.FindIncludePaths(locsys)
+	}
+	index := newSymbolIndex(src, locsys)
+	var unused []UnusedInclude
+	filenames := append(append([]string{}, src.absFilenamesC...), src.absFilenamesCPP...)
+	sort.Strings(filenames)
+	for _, filename := range filenames {
+		f, ok := src.files[filename]
+		if !ok || len(f.includes) == 0 {
+			continue
+		}
+		used := Identifiers(f.data)
+		conditional := conditionalLines(f.data)
+		for _, include := range f.includes {
+			header, ok := index.resolve(include)
+			if !ok || isOwnHeader(filename, header) {
+				continue
+			}
+			names, complete := index.symbols(header)
+			referenced := false
+			for name := range names {
+				if used[name] {
+					referenced = true
+					break
+				}
+			}
+			if referenced {
+				continue
+			}
+			result := UnusedInclude{Include: include, Header: header, Symbols: len(names), Confidence: ConfidenceHigh}
+			switch {
+			case len(names) == 0:
+				result.Confidence = ConfidenceLow
+				result.Reason = "no declarations were found in " + include.Name + ", it may only have side effects"
+			case !complete:
+				result.Confidence = ConfidenceMedium
+				result.Reason = fmt.Sprintf("none of the %d symbols of %s are used, but some of the headers it includes could not be indexed", len(names), include.Name)
+			case conditional[include.Line]:
+				result.Confidence = ConfidenceMedium
+				result.Reason = fmt.Sprintf("none of the %d symbols of %s are used, but the #include line is within an #if block", len(names), include.Name)
+			default:
+				result.Reason = fmt.Sprintf("none of the %d symbols of %s are used", len(names), include.Name)
+			}
+			unused = append(unused, result)
+		}
+	}
+	return unused
+}
@@ -0,0 +1,91 @@
+package autocpp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseHeaderSymbols(t *testing.T) {
+	header := `#ifndef WIDGET_H
+#define WIDGET_H
+
+#include <stdint.h>
+
+#define WIDGET_MAX 10 // the maximum number of widgets
+#define WIDGET_MIN(a, b) \
+	((a) < (b) ? (a) : (b))
+
+/* class NotAClass { }; */
+namespace ui {
+class Widget;
+class Button : public Base {
+public:
+	void click(int times);
+};
+template <typename T>
+struct Box { T value; };
+enum class Color { Red, Green = 2, Blue };
+typedef void (*callback)(int);
+typedef struct { int x; } point_t;
+using Size = unsigned int;
+int widget_count(void);
+inline int twice(int x) { return x * 2; }
+extern const char *widget_name;
+}
+
+extern "C" {
+int c_function(const char *s);
+}
+#endif
+`
+	symbols := ParseHeaderSymbols([]byte(header))
+	expected := HeaderSymbols{
+		Functions: []string{"c_function", "twice", "widget_count"},
+		Classes:   []string{"Box", "Button", "Color", "Widget"},
+		Macros:    []string{"WIDGET_MAX", "WIDGET_MIN"},
+		Typedefs:  []string{"Size", "callback", "point_t"},
+		Variables: []string{"Blue", "Green", "Red", "widget_name"},
+	}
+	if !reflect.DeepEqual(symbols, expected) {
+		t.Errorf("unexpected symbols:\n%+v\nexpected:\n%+v", symbols, expected)
+	}
+}
+
+func TestUnusedIncludes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.cpp": {Data: []byte(`#include "main.h"
+#include <stdio.h>
+#include <math.h>
+#include <cstdint>
+#include "util.h"
+#include "config.h"
+#ifdef DEBUG
+#include "debug.h"
+#endif
+
+int main() {
+	uint32_t x = 1; // sqrt(2.0)
+	printf("helper(%d)\n", x);
+	return 0;
+}
+`)},
+		"main.h":   {Data: []byte("#pragma once\n")},
+		"util.h":   {Data: []byte("#pragma once\nint helper(int x);\n")},
+		"config.h": {Data: []byte("#pragma once\n")},
+		"debug.h":  {Data: []byte("#pragma once\n#define TRACE(x)\n")},
+	}
+	src, err := NewSourcesWithOptions("project", SourcesOptions{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []string
+	for _, include := range src.UnusedIncludes(newTestSystem(t)) {
+		results = append(results, include.Name+" "+include.Confidence.String())
+	}
+	expected := []string{"math.h high", "util.h high", "config.h low", "debug.h medium"}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("unexpected unused includes:\n%s", strings.Join(results, "\n"))
+	}
+}
@@ -0,0 +1,122 @@
+package autocpp
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"sort"
+	"sync"
+)
+
+// walkVisitor decides what happens to the entries of a directory while walking in parallel.
+// S is state that is passed from a directory to its subdirectories, like the gitignore patterns.
+type walkVisitor[S any] struct {
+	// enter is called for each directory that is walked, before its entries are visited,
+	// and returns the state that is used for the entries of the directory
+	enter func(dir string, state S) S
+	// skipDir checks if the given subdirectory should be skipped
+	skipDir func(p string, d fs.DirEntry, state S) bool
+	// keepFile checks if the given file should be part of the result
+	keepFile func(p string, d fs.DirEntry, state S) bool
+	// readDir reads the entries of a directory. If nil, fs.ReadDir is used.
+	readDir func(dir string) ([]fs.DirEntry, error)
+}
+
+// walkParallel walks the given directory within fsys, with a fixed number of concurrency workers that
+// read the directories from a shared queue. The slash-separated paths of the files that are kept are
+// returned in sorted order, regardless of the order the directories were read in. The walk stops early
+// if ctx is canceled.
+func walkParallel[S any](ctx context.Context, fsys fs.FS, root string, rootState S, concurrency int, visitor walkVisitor[S]) ([]string, error) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	type queuedDir struct {
+		dir   string
+		state S
+	}
+	// readDir reads a single directory, and returns the files that are kept and the subdirectories to walk
+	readDir := func(dir string, state S) ([]string, []queuedDir, error) {
+		if visitor.enter != nil {
+			state = visitor.enter(dir, state)
+		}
+		var (
+			entries []fs.DirEntry
+			err     error
+		)
+		if visitor.readDir != nil {
+			entries, err = visitor.readDir(dir)
+		} else {
+			entries, err = fs.ReadDir(fsys, dir)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		var (
+			kept    []string
+			subdirs []queuedDir
+		)
+		for _, d := range entries {
+			p := path.Join(dir, d.Name())
+			if d.IsDir() {
+				if visitor.skipDir == nil || !visitor.skipDir(p, d, state) {
+					subdirs = append(subdirs, queuedDir{p, state})
+				}
+				continue
+			}
+			if visitor.keepFile == nil || visitor.keepFile(p, d, state) {
+				kept = append(kept, p)
+			}
+		}
+		return kept, subdirs, nil
+	}
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		ready    = sync.NewCond(&mu) // signaled when directories are queued, or when the walk is done
+		queue    = []queuedDir{{root, rootState}}
+		pending  = 1 // the number of directories that are queued or being read
+		results  []string
+		firstErr error
+	)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			for {
+				for len(queue) == 0 && pending > 0 {
+					ready.Wait()
+				}
+				if len(queue) == 0 {
+					return
+				}
+				next := queue[0]
+				queue = queue[1:]
+				mu.Unlock()
+				var (
+					kept    []string
+					subdirs []queuedDir
+					err     = ctx.Err()
+				)
+				if err == nil {
+					kept, subdirs, err = readDir(next.dir, next.state)
+				}
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				results = append(results, kept...)
+				queue = append(queue, subdirs...)
+				pending += len(subdirs) - 1
+				ready.Broadcast()
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sort.Strings(results)
+	return results, firstErr
+}
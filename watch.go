@@ -0,0 +1,309 @@
+package autocpp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// WatchEventKind is the kind of a WatchEvent
+type WatchEventKind int
+
+const (
+	// EventSourcesChanged is sent when source files were added, removed or changed
+	EventSourcesChanged WatchEventKind = iota
+	// EventUnresolvedInclude is sent when an include can no longer be found, or a new include can not be found
+	EventUnresolvedInclude
+	// EventIncludeResolved is sent when an include that could not be found is found, or is no longer included
+	EventIncludeResolved
+	// EventLibraryNeeded is sent when the sources start to include headers from a known library
+	EventLibraryNeeded
+	// EventLibraryNotNeeded is sent when the sources no longer include headers from a known library
+	EventLibraryNotNeeded
+	// EventCycleIntroduced is sent when project files start to include each other in a cycle
+	EventCycleIntroduced
+	// EventCycleRemoved is sent when an include cycle is broken
+	EventCycleRemoved
+	// EventError is sent when the sources or the system include directories could not be read
+	EventError
+)
+
+var watchEventKindNames = []string{
+	EventSourcesChanged:    "sources-changed",
+	EventUnresolvedInclude: "unresolved-include",
+	EventIncludeResolved:   "include-resolved",
+	EventLibraryNeeded:     "library-needed",
+	EventLibraryNotNeeded:  "library-not-needed",
+	EventCycleIntroduced:   "cycle-introduced",
+	EventCycleRemoved:      "cycle-removed",
+	EventError:             "error",
+}
+
+// String returns a name like "unresolved-include"
+func (kind WatchEventKind) String() string {
+	if kind < 0 || int(kind) >= len(watchEventKindNames) {
+		return "unknown"
+	}
+	return watchEventKindNames[kind]
+}
+
+// MarshalText makes the kind appear by name in JSON
+func (kind WatchEventKind) MarshalText() ([]byte, error) {
+	return []byte(kind.String()), nil
+}
+
+// WatchEvent is sent by a Watcher when something changes that an editor may want to show
+type WatchEvent struct {
+	Kind    WatchEventKind `json:"kind"`
+	Include string         `json:"include,omitempty"` // the short include name, for unresolved and resolved includes
+	Library string         `json:"library,omitempty"` // the pkg-config name, for library events
+	Cycle   []string       `json:"cycle,omitempty"`   // the sorted files in the cycle, for cycle events
+	Diff    *SourcesDiff   `json:"diff,omitempty"`    // what changed, for EventSourcesChanged
+	Err     error          `json:"-"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// String returns a short description of the event, for logging
+func (event WatchEvent) String() string {
+	switch event.Kind {
+	case EventUnresolvedInclude, EventIncludeResolved:
+		return event.Kind.String() + ": " + event.Include
+	case EventLibraryNeeded, EventLibraryNotNeeded:
+		return event.Kind.String() + ": " + event.Library
+	case EventCycleIntroduced, EventCycleRemoved:
+		return event.Kind.String() + ": " + strings.Join(event.Cycle, " -> ")
+	case EventError:
+		return event.Kind.String() + ": " + event.Error
+	}
+	return event.Kind.String()
+}
+
+// WatcherOptions can be used for configuring a Watcher
+type WatcherOptions struct {
+	// Debounce is how long to wait for more changes after a file changed, before refreshing.
+	// If zero, 100ms is used.
+	Debounce time.Duration
+	// PollInterval is how often the sources are refreshed if file system notifications are not
+	// available, like for sources that are not on the local file system. If zero, 2s is used.
+	PollInterval time.Duration
+	// Poll can be set to always poll, instead of using file system notifications
+	Poll bool
+	// WatchSystem can be set to also rebuild the index of the LocalSystem when a system include
+	// directory changes, like when a package is installed. This needs file system notifications.
+	WatchSystem bool
+}
+
+// Watcher keeps Sources and a LocalSystem up to date as files change, and sends events about
+// what changed on the Events channel. On Linux, inotify is used for noticing changes.
+// The Sources and the LocalSystem must not be used by others while the Watcher runs.
+type Watcher struct {
+	// Events receives the events. It is closed when Run returns.
+	Events     chan WatchEvent
+	src        *Sources
+	locsys     *LocalSystem
+	opts       WatcherOptions
+	srcChanged chan struct{}
+	sysChanged chan struct{}
+	state      watchState
+}
+
+// watchState is what the events are made from, after each refresh
+type watchState struct {
+	notFound  []string
+	libraries []string
+	cycles    [][]string
+}
+
+// errNoNotify is returned when file system notifications can not be used
+var errNoNotify = errors.New("file system notifications are not available")
+
+// NewWatcher returns a Watcher for the given Sources. If locsys is nil, no includes are resolved,
+// so only library and cycle events are sent.
+func NewWatcher(src *Sources, locsys *LocalSystem, opts WatcherOptions) *Watcher {
+	if opts.Debounce <= 0 {
+		opts.Debounce = 100 * time.Millisecond
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 2 * time.Second
+	}
+	return &Watcher{
+		Events:     make(chan WatchEvent, 64),
+		src:        src,
+		locsys:     locsys,
+		opts:       opts,
+		srcChanged: make(chan struct{}, 1),
+		sysChanged: make(chan struct{}, 1),
+	}
+}
+
+// Run watches the files until the given context is canceled. At first, an event is sent for every
+// include that can not be found, every library that is needed and every include cycle, and after that,
+// only for what changed. Run returns nil when the context is canceled.
+func (w *Watcher) Run(ctx context.Context) error {
+	defer close(w.Events)
+	if w.locsys != nil {
+		w.src.FindIncludePaths(w.locsys)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	notifyErr := errNoNotify
+	if !w.opts.Poll {
+		notifyErr = w.notify(ctx)
+	}
+	var ticks <-chan time.Time
+	if notifyErr != nil {
+		logf(w.src.output, "polling every %v, since %v\n", w.opts.PollInterval, notifyErr)
+		ticker := time.NewTicker(w.opts.PollInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+	if !w.update(ctx, nil) {
+		return nil
+	}
+	var (
+		timer         = time.NewTimer(w.opts.Debounce)
+		pending       bool
+		pendingSystem bool
+	)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticks:
+			if !w.refresh(ctx, false) {
+				return nil
+			}
+		case <-w.srcChanged:
+			pending = true
+			timer.Reset(w.opts.Debounce)
+		case <-w.sysChanged:
+			pendingSystem = true
+			timer.Reset(w.opts.Debounce)
+		case <-timer.C:
+			if pending || pendingSystem {
+				if !w.refresh(ctx, pendingSystem) {
+					return nil
+				}
+			}
+			pending, pendingSystem = false, false
+		}
+	}
+}
+
+// signal marks that something changed, without blocking if a change is already pending
+func signal(changed chan<- struct{}) {
+	select {
+	case changed <- struct{}{}:
+	default:
+	}
+}
+
+// refresh refreshes the sources, and the LocalSystem if system changed, and sends events.
+// It returns false if the context was canceled.
+func (w *Watcher) refresh(ctx context.Context, system bool) bool {
+	if system && w.locsys != nil {
+		if err := w.locsys.RebuildIndex(ctx); err != nil {
+			return w.send(ctx, errorEvent(err))
+		}
+	}
+	diff, err := w.src.RefreshContext(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false
+		}
+		return w.send(ctx, errorEvent(err))
+	}
+	if system && w.locsys != nil {
+		// Includes that could not be found before may have been installed, and found includes may be gone
+		w.src.FindIncludePaths(w.locsys)
+	}
+	return w.update(ctx, diff)
+}
+
+// update compares the current state to the previous one and sends the events.
+// It returns false if the context was canceled.
+func (w *Watcher) update(ctx context.Context, diff *SourcesDiff) bool {
+	newState := w.currentState()
+	var events []WatchEvent
+	if diff != nil && !diff.Empty() {
+		events = append(events, WatchEvent{Kind: EventSourcesChanged, Diff: diff})
+	}
+	events = append(events, diffWatchStates(w.state, newState)...)
+	w.state = newState
+	for _, event := range events {
+		if !w.send(ctx, event) {
+			return false
+		}
+	}
+	return true
+}
+
+// send sends an event, unless the context is canceled first
+func (w *Watcher) send(ctx context.Context, event WatchEvent) bool {
+	select {
+	case w.Events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func errorEvent(err error) WatchEvent {
+	return WatchEvent{Kind: EventError, Err: err, Error: err.Error()}
+}
+
+// currentState collects the unresolved includes, needed libraries and include cycles of the sources
+func (w *Watcher) currentState() watchState {
+	var state watchState
+	if w.locsys != nil {
+		for _, include := range w.src.ShortIncludes() {
+			if _, ok := w.src.foundMap[include]; !ok {
+				state.notFound = append(state.notFound, include)
+			}
+		}
+	}
+	state.libraries = w.src.Libraries()
+	state.cycles = w.src.Cycles()
+	return state
+}
+
+// diffWatchStates returns the events for going from one state to another
+func diffWatchStates(oldState, newState watchState) []WatchEvent {
+	var events []WatchEvent
+	for _, include := range subtractS(newState.notFound, oldState.notFound) {
+		events = append(events, WatchEvent{Kind: EventUnresolvedInclude, Include: include})
+	}
+	for _, include := range subtractS(oldState.notFound, newState.notFound) {
+		events = append(events, WatchEvent{Kind: EventIncludeResolved, Include: include})
+	}
+	for _, library := range subtractS(newState.libraries, oldState.libraries) {
+		events = append(events, WatchEvent{Kind: EventLibraryNeeded, Library: library})
+	}
+	for _, library := range subtractS(oldState.libraries, newState.libraries) {
+		events = append(events, WatchEvent{Kind: EventLibraryNotNeeded, Library: library})
+	}
+	key := func(cycle []string) string {
+		return strings.Join(cycle, "\x00")
+	}
+	var oldCycles, newCycles []string
+	for _, cycle := range oldState.cycles {
+		oldCycles = append(oldCycles, key(cycle))
+	}
+	for _, cycle := range newState.cycles {
+		newCycles = append(newCycles, key(cycle))
+	}
+	for _, cycle := range newState.cycles {
+		if !hasS(oldCycles, key(cycle)) {
+			events = append(events, WatchEvent{Kind: EventCycleIntroduced, Cycle: cycle})
+		}
+	}
+	for _, cycle := range oldState.cycles {
+		if !hasS(newCycles, key(cycle)) {
+			events = append(events, WatchEvent{Kind: EventCycleRemoved, Cycle: cycle})
+		}
+	}
+	return events
+}
@@ -0,0 +1,128 @@
+//go:build linux
+
+package autocpp
+
+import (
+	"context"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_ONLYDIR
+
+// inotify watches directories with the inotify API of Linux
+type inotify struct {
+	fd     int
+	file   *os.File // wraps fd, so that reading blocks in the runtime poller and can be stopped by closing
+	mu     sync.Mutex
+	dirs   map[int]string // from watch descriptor to directory
+	system map[int]bool   // watch descriptors of system include directories
+}
+
+// notify starts watching the directories of the sources, and the system include directories if
+// opts.WatchSystem is set, until the context is canceled
+func (w *Watcher) notify(ctx context.Context) error {
+	if !w.src.osFS {
+		return errNoNotify
+	}
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return os.NewSyscallError("inotify_init1", err)
+	}
+	in := &inotify{
+		fd:     fd,
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		dirs:   make(map[int]string),
+		system: make(map[int]bool),
+	}
+	if err := in.addSources(ctx, w.src); err != nil {
+		in.file.Close()
+		return err
+	}
+	if w.opts.WatchSystem && w.locsys != nil && w.locsys.osFS {
+		// Only the system include directories themselves are watched, which is enough for noticing
+		// that a package added a directory like /usr/include/SDL2 or a header like /usr/include/png.h
+		for _, dir := range w.locsys.allSystemIncludeDirectories() {
+			if wd, err := syscall.InotifyAddWatch(in.fd, dir, inotifyMask); err == nil {
+				in.mu.Lock()
+				in.dirs[wd] = dir
+				in.system[wd] = true
+				in.mu.Unlock()
+			}
+		}
+	}
+	go func() {
+		<-ctx.Done()
+		in.file.Close()
+	}()
+	go in.read(ctx, w)
+	return nil
+}
+
+// addSources watches every directory of the sources that is not excluded or ignored.
+// Directories that are already watched are skipped by inotify.
+func (in *inotify) addSources(ctx context.Context, src *Sources) error {
+	var firstErr error
+	_, err := src.walkAndVisit(ctx, func(dir string) {
+		name := src.name(dir)
+		wd, err := syscall.InotifyAddWatch(in.fd, name, inotifyMask)
+		in.mu.Lock()
+		defer in.mu.Unlock()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = os.NewSyscallError("inotify_add_watch", err)
+			}
+			return
+		}
+		in.dirs[wd] = name
+	})
+	if err != nil {
+		return err
+	}
+	return firstErr
+}
+
+// read reads events until the inotify file is closed, and signals the watcher
+func (in *inotify) read(ctx context.Context, w *Watcher) {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := in.file.Read(buf)
+		if err != nil {
+			return
+		}
+		var newDirs bool
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+			wd := int(event.Wd)
+			in.mu.Lock()
+			system := in.system[wd]
+			if event.Mask&syscall.IN_IGNORED != 0 {
+				delete(in.dirs, wd)
+				delete(in.system, wd)
+			}
+			in.mu.Unlock()
+			switch {
+			case event.Mask&syscall.IN_Q_OVERFLOW != 0:
+				newDirs = true
+				signal(w.srcChanged)
+			case system:
+				signal(w.sysChanged)
+			default:
+				if event.Mask&syscall.IN_ISDIR != 0 && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+					newDirs = true
+				}
+				signal(w.srcChanged)
+			}
+		}
+		if newDirs {
+			// Watch new directories, like after "mkdir -p src/a/b" or after the queue overflowed
+			if err := in.addSources(ctx, w.src); err != nil && ctx.Err() == nil {
+				logf(w.src.output, "%v\n", err)
+			}
+		}
+	}
+}
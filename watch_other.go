@@ -0,0 +1,10 @@
+//go:build !linux
+
+package autocpp
+
+import "context"
+
+// notify is only implemented for Linux, so other systems poll for changes
+func (w *Watcher) notify(ctx context.Context) error {
+	return errNoNotify
+}
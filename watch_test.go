@@ -0,0 +1,137 @@
+package autocpp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestCycles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.cpp":    {Data: []byte("#include \"a.h\"\n#include \"lib/c.h\"\n")},
+		"a.h":         {Data: []byte("#include \"b.h\"\n")},
+		"b.h":         {Data: []byte("#include \"a.h\"\n#include <vector>\n")},
+		"lib/c.h":     {Data: []byte("#include \"d.h\"\n")},
+		"lib/d.h":     {Data: []byte("#include \"d.h\"\n")},
+		"lib/other.h": {Data: []byte("#include \"../a.h\"\n")},
+	}
+	src, err := NewSourcesWithOptions("project", SourcesOptions{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph := src.IncludeGraph()
+	if expected := []string{filepath.Join("project", "a.h"), filepath.Join("project", "lib", "c.h")}; !reflect.DeepEqual(graph[filepath.Join("project", "main.cpp")], expected) {
+		t.Errorf("expected main.cpp to include %v, got %v", expected, graph[filepath.Join("project", "main.cpp")])
+	}
+	if expected := []string{filepath.Join("project", "a.h")}; !reflect.DeepEqual(graph[filepath.Join("project", "lib", "other.h")], expected) {
+		t.Errorf("expected lib/other.h to include %v, got %v", expected, graph[filepath.Join("project", "lib", "other.h")])
+	}
+	expected := [][]string{
+		{filepath.Join("project", "a.h"), filepath.Join("project", "b.h")},
+		{filepath.Join("project", "lib", "d.h")},
+	}
+	if cycles := src.Cycles(); !reflect.DeepEqual(cycles, expected) {
+		t.Errorf("expected cycles %v, got %v", expected, cycles)
+	}
+}
+
+func TestDiffWatchStates(t *testing.T) {
+	oldState := watchState{
+		notFound:  []string{"missing.h", "gone.h"},
+		libraries: []string{"sdl2"},
+		cycles:    [][]string{{"a.h", "b.h"}},
+	}
+	newState := watchState{
+		notFound:  []string{"missing.h", "new.h"},
+		libraries: []string{"sdl2", "zlib"},
+		cycles:    [][]string{{"c.h", "d.h"}},
+	}
+	expected := []WatchEvent{
+		{Kind: EventUnresolvedInclude, Include: "new.h"},
+		{Kind: EventIncludeResolved, Include: "gone.h"},
+		{Kind: EventLibraryNeeded, Library: "zlib"},
+		{Kind: EventCycleIntroduced, Cycle: []string{"c.h", "d.h"}},
+		{Kind: EventCycleRemoved, Cycle: []string{"a.h", "b.h"}},
+	}
+	if events := diffWatchStates(oldState, newState); !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+	if events := diffWatchStates(newState, newState); len(events) != 0 {
+		t.Errorf("expected no events for the same state, got %v", events)
+	}
+}
+
+// waitForEvent waits for an event of the given kind, skipping other events
+func waitForEvent(t *testing.T, events <-chan WatchEvent, kind WatchEventKind) WatchEvent {
+	t.Helper()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("the watcher stopped while waiting for %s", kind)
+			}
+			if event.Kind == kind {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("timed out while waiting for %s", kind)
+		}
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, "main.cpp", "a.h")
+	write := func(name, contents string) {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("main.cpp", "#include \"a.h\"\n#include <vector>\n#include <nothere.h>\n")
+	src, err := NewSourcesWithOptions(root, SourcesOptions{LocalIncludeDirectories: []string{"."}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, poll := range []bool{false, true} {
+		if poll && t.Failed() {
+			break
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		watcher := NewWatcher(src, newTestSystem(t), WatcherOptions{Debounce: 10 * time.Millisecond, PollInterval: 20 * time.Millisecond, Poll: poll})
+		done := make(chan error, 1)
+		go func() {
+			done <- watcher.Run(ctx)
+		}()
+		// The first events are sent when the watcher is ready
+		for event := waitForEvent(t, watcher.Events, EventUnresolvedInclude); event.Include != "nothere.h"; {
+			event = waitForEvent(t, watcher.Events, EventUnresolvedInclude)
+		}
+		if poll {
+			write("main.cpp", "#include \"a.h\"\n#include <vector>\n")
+			if event := waitForEvent(t, watcher.Events, EventIncludeResolved); event.Include != "nothere.h" {
+				t.Errorf("expected nothere.h to no longer be unresolved, got %s", event)
+			}
+		} else {
+			os.Mkdir(filepath.Join(root, "sub"), 0o755)
+			write("sub/b.h", "#include \"../a.h\"\n")
+			write("a.h", "#include \"sub/b.h\"\n#include <SDL2/SDL.h>\n#include <missing.h>\n")
+			if event := waitForEvent(t, watcher.Events, EventCycleIntroduced); !reflect.DeepEqual(event.Cycle, []string{filepath.Join(root, "a.h"), filepath.Join(root, "sub", "b.h")}) {
+				t.Errorf("unexpected cycle: %v", event.Cycle)
+			}
+			write("sub/b.h", "#include <zlib.h>\n")
+			if event := waitForEvent(t, watcher.Events, EventLibraryNeeded); event.Library != "zlib" {
+				t.Errorf("expected zlib to be needed, got %s", event)
+			}
+			waitForEvent(t, watcher.Events, EventCycleRemoved)
+		}
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}
+}
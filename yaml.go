@@ -0,0 +1,238 @@
+package autocpp
+
+import (
+	"bufio"
+	"encoding"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// plainYAMLString matches strings that can be written without quotes in YAML
+var plainYAMLString = regexp.MustCompile(`^[A-Za-z0-9_./+][A-Za-z0-9_./+=<>@ ,()-]*$`)
+
+// yamlEncoder writes values as YAML, using the same field names as encoding/json.
+// Only the types that are used by the reports of this package need to be supported.
+type yamlEncoder struct {
+	w *bufio.Writer
+}
+
+// yamlField is a key and value of a YAML mapping
+type yamlField struct {
+	key   string
+	value reflect.Value
+}
+
+// writeYAML writes the given value as a YAML document
+func writeYAML(w io.Writer, v interface{}) error {
+	e := yamlEncoder{w: bufio.NewWriter(w)}
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	if fields, ok := e.fields(value); ok {
+		if len(fields) == 0 {
+			e.w.WriteString("{}\n")
+		} else {
+			e.mapping(fields, 0, false)
+		}
+	} else if isYAMLSequence(value) {
+		if value.Len() == 0 {
+			e.w.WriteString("[]\n")
+		} else {
+			e.sequence(value, 0)
+		}
+	} else {
+		e.w.WriteString(e.scalar(value) + "\n")
+	}
+	return e.w.Flush()
+}
+
+// isYAMLSequence checks if the value is a slice or array, but not a []byte
+func isYAMLSequence(v reflect.Value) bool {
+	return (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8
+}
+
+// fields returns the entries of a struct or map, and false if the value is not a struct or map
+func (e *yamlEncoder) fields(v reflect.Value) ([]yamlField, bool) {
+	if _, ok := textMarshaler(v); ok {
+		return nil, false
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		var fields []yamlField
+		for _, key := range v.MapKeys() {
+			fields = append(fields, yamlField{key: fmt.Sprint(key.Interface()), value: v.MapIndex(key)})
+		}
+		sort.Slice(fields, func(i, j int) bool {
+			return fields[i].key < fields[j].key
+		})
+		return fields, true
+	case reflect.Struct:
+		var fields []yamlField
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" && !field.Anonymous {
+				continue // not exported
+			}
+			name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			value := v.Field(i)
+			if field.Anonymous && name == "" && value.Kind() == reflect.Struct {
+				embedded, _ := e.fields(value)
+				fields = append(fields, embedded...)
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if strings.Contains(options, "omitempty") && isEmptyYAMLValue(value) {
+				continue
+			}
+			fields = append(fields, yamlField{key: name, value: value})
+		}
+		return fields, true
+	}
+	return nil, false
+}
+
+// isEmptyYAMLValue checks if a field with the omitempty option is left out, like for encoding/json
+func isEmptyYAMLValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// mapping writes the fields at the given indentation. If inSequence is true, the first field
+// follows a "- " that has already been written.
+func (e *yamlEncoder) mapping(fields []yamlField, indent int, inSequence bool) {
+	for i, field := range fields {
+		if i > 0 || !inSequence {
+			e.w.WriteString(strings.Repeat(" ", indent))
+		}
+		e.w.WriteString(e.quote(field.key) + ":")
+		e.value(field.value, indent+2)
+	}
+}
+
+// sequence writes the elements of a slice or array at the given indentation
+func (e *yamlEncoder) sequence(v reflect.Value, indent int) {
+	for i := 0; i < v.Len(); i++ {
+		e.w.WriteString(strings.Repeat(" ", indent) + "-")
+		element := v.Index(i)
+		for element.Kind() == reflect.Ptr || element.Kind() == reflect.Interface {
+			if element.IsNil() {
+				break
+			}
+			element = element.Elem()
+		}
+		if fields, ok := e.fields(element); ok && len(fields) > 0 {
+			e.w.WriteString(" ")
+			e.mapping(fields, indent+2, true)
+			continue
+		}
+		e.value(element, indent+2)
+	}
+}
+
+// value writes a value after a "key:" or "-" that has already been written
+func (e *yamlEncoder) value(v reflect.Value, indent int) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			e.w.WriteString(" null\n")
+			return
+		}
+		v = v.Elem()
+	}
+	if fields, ok := e.fields(v); ok {
+		if len(fields) == 0 {
+			e.w.WriteString(" {}\n")
+			return
+		}
+		e.w.WriteString("\n")
+		e.mapping(fields, indent, false)
+		return
+	}
+	if isYAMLSequence(v) {
+		if v.Len() == 0 {
+			e.w.WriteString(" []\n")
+			return
+		}
+		e.w.WriteString("\n")
+		e.sequence(v, indent)
+		return
+	}
+	e.w.WriteString(" " + e.scalar(v) + "\n")
+}
+
+// textMarshaler returns the text of values that implement encoding.TextMarshaler
+func textMarshaler(v reflect.Value) (string, bool) {
+	if !v.IsValid() || !v.CanInterface() {
+		return "", false
+	}
+	if marshaler, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := marshaler.MarshalText()
+		if err == nil {
+			return string(text), true
+		}
+	}
+	return "", false
+}
+
+// scalar returns a string, number or bool as YAML
+func (e *yamlEncoder) scalar(v reflect.Value) string {
+	if text, ok := textMarshaler(v); ok {
+		return e.quote(text)
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return e.quote(v.String())
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case reflect.Invalid:
+		return "null"
+	}
+	return e.quote(fmt.Sprint(v.Interface()))
+}
+
+// quote quotes a string, unless it can be written as a plain YAML string that is not mistaken for
+// a number, bool or null
+func (e *yamlEncoder) quote(s string) string {
+	if plainYAMLString.MatchString(s) && !strings.HasSuffix(s, " ") {
+		switch strings.ToLower(s) {
+		case "true", "false", "yes", "no", "on", "off", "y", "n", "null", "~":
+		default:
+			_, floatErr := strconv.ParseFloat(s, 64)
+			_, intErr := strconv.ParseInt(s, 0, 64)
+			if floatErr != nil && intErr != nil && !strings.HasPrefix(s, ".") {
+				return s
+			}
+		}
+	}
+	return strconv.Quote(s)
+}